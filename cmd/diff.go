@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// unifiedDiff returns unified diff of a and b. Lines are compared pairwise
+// which is enough for the formatter as it never adds or removes lines
+func unifiedDiff(name string, a, b []byte) string {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	n := max(len(aLines), len(bLines))
+	changed := make([]bool, n)
+	for i := range n {
+		changed[i] = i >= len(aLines) || i >= len(bLines) || aLines[i] != bLines[i]
+	}
+
+	sb := strings.Builder{}
+	for start := 0; start < n; {
+		if !changed[start] {
+			start++
+			continue
+		}
+		// Extend the hunk while changes are closer than two contexts
+		end := start
+		for i := start; i < n && i <= end+2*diffContext; i++ {
+			if changed[i] {
+				end = i
+			}
+		}
+		from := max(0, start-diffContext)
+		to := min(n, end+diffContext+1)
+		if sb.Len() == 0 {
+			sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", name, name))
+		}
+		aCount := min(to, len(aLines)) - from
+		bCount := min(to, len(bLines)) - from
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", from+1, aCount, from+1, bCount))
+		for i := from; i < to; {
+			if !changed[i] {
+				writeDiffLine(&sb, ' ', aLines[i])
+				i++
+				continue
+			}
+			// Removed lines of the run go before added ones
+			runEnd := i
+			for runEnd < to && changed[runEnd] {
+				runEnd++
+			}
+			for j := i; j < min(runEnd, len(aLines)); j++ {
+				writeDiffLine(&sb, '-', aLines[j])
+			}
+			for j := i; j < min(runEnd, len(bLines)); j++ {
+				writeDiffLine(&sb, '+', bLines[j])
+			}
+			i = runEnd
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+func splitLines(b []byte) []string {
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeDiffLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
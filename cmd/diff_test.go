@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+	b := []byte("1\n2x\n3\n4\n5\n6\n7\n8\n9\n10\n11x\n12\n")
+	expected := `--- f
++++ f
+@@ -1,5 +1,5 @@
+ 1
+-2
++2x
+ 3
+ 4
+ 5
+@@ -8,5 +8,5 @@
+ 8
+ 9
+ 10
+-11
++11x
+ 12
+`
+	assert.Equal(t, expected, unifiedDiff("f", a, b))
+	assert.Equal(t, "", unifiedDiff("f", a, a))
+
+	expected = `--- f
++++ f
+@@ -1,2 +1,2 @@
+-1
+-2
++1x
++2x
+`
+	assert.Equal(t, expected, unifiedDiff("f", []byte("1\n2\n"), []byte("1x\n2x\n")))
+}
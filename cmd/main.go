@@ -2,12 +2,23 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/alaruss/geancount/geancount"
 	"github.com/urfave/cli/v2"
+	_ "modernc.org/sqlite"
 )
 
 func init() {
@@ -38,18 +49,471 @@ func printBalances(cCtx *cli.Context) error {
 }
 
 func checkLedger(cCtx *cli.Context) error {
-	errs := []error{}
 	filename := cCtx.Args().Get(0)
+	ledger, _, err := loadLedger(filename)
+	diagnostics := append(geancount.Diagnostics(err), ledger.Warnings()...)
+	geancount.SortDiagnostics(diagnostics)
+	renderer := geancount.NewDiagnosticRenderer(os.ReadFile, isTerminal(os.Stdout))
+	for _, d := range diagnostics {
+		renderer.Render(os.Stdout, d)
+	}
+	if geancount.HasErrors(diagnostics) {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// isTerminal checks if colored output can be written to the file
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// loadLedger loads the file and computes its state, errors of both steps are joined
+func loadLedger(filename string) (*geancount.Ledger, geancount.LedgerState, error) {
+	errs := []error{}
 	ledger := geancount.NewLedger()
 	err := ledger.LoadFile(filename)
 	if err != nil {
 		errs = append(errs, err)
 	}
-	_, err = ledger.GetState()
+	ls, err := ledger.GetState()
 	if err != nil {
 		errs = append(errs, err)
 	}
-	return errors.Join(errs...)
+	return ledger, ls, errors.Join(errs...)
+}
+
+func writeHTML(cCtx *cli.Context) error {
+	filename := cCtx.Args().Get(0)
+	ledger, ls, err := loadLedger(filename)
+	if err != nil {
+		fmt.Printf("%s\n\n", err)
+	}
+	return ledger.WriteHTML(ls, err, cCtx.String("out"))
+}
+
+// export writes the ledger to the database given in arguments. Nothing is written if the ledger has errors
+// because the export is a contract of complete and valid data
+func export(cCtx *cli.Context) error {
+	if format := cCtx.String("format"); format != "sqlite" {
+		return fmt.Errorf("Unknown export format %s", format)
+	}
+	if cCtx.NArg() != 1 {
+		return errors.New("a database file should be given")
+	}
+	ledger, ls, err := loadLedger(cCtx.String("ledger"))
+	if err != nil {
+		return fmt.Errorf("ledger is not exported because of errors:\n%w", err)
+	}
+	db, err := sql.Open("sqlite", cCtx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return ledger.ExportSQL(ls, db)
+}
+
+func serve(cCtx *cli.Context) error {
+	filename := cCtx.Args().Get(0)
+	server := geancount.NewServer(filename)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Watch(ctx, cCtx.Duration("interval"))
+	addr := cCtx.String("addr")
+	log.Printf("Serving %s on http://%s", filename, addr)
+	return http.ListenAndServe(addr, server)
+}
+
+func serveMetrics(cCtx *cli.Context) error {
+	filename := cCtx.Args().Get(0)
+	server := geancount.NewMetricsServer(filename)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Watch(ctx, cCtx.Duration("interval"))
+	addr := cCtx.String("addr")
+	log.Printf("Serving metrics of %s on http://%s/metrics", filename, addr)
+	return http.ListenAndServe(addr, server)
+}
+
+func serveLSP(cCtx *cli.Context) error {
+	server := geancount.NewLSPServer(cCtx.Args().Get(0), os.Stdin, os.Stdout)
+	return server.Run()
+}
+
+// importStatements converts every statement given in arguments with convert and prints directives.
+// The ledger passed to convert is nil if it is not given
+func importStatements(cCtx *cli.Context, convert func(io.Reader, *geancount.Ledger) ([]geancount.Directive, error)) error {
+	var ledger *geancount.Ledger
+	if filename := cCtx.String("ledger"); filename != "" {
+		ledger = geancount.NewLedger()
+		// Errors of the ledger are not important for import unless nothing is loaded
+		if err := ledger.LoadFile(filename); err != nil && len(ledger.Directives()) == 0 {
+			return err
+		}
+	} else if cCtx.Bool("learn") {
+		return errors.New("learning requires the ledger")
+	}
+	directives := []geancount.Directive{}
+	errs := []error{}
+	for _, filename := range cCtx.Args().Slice() {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		imported, err := convert(file, ledger)
+		file.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+		}
+		directives = append(directives, imported...)
+	}
+	rules := geancount.Rules{}
+	if filename := cCtx.String("rules"); filename != "" {
+		var err error
+		if rules, err = geancount.LoadRules(filename); err != nil {
+			return err
+		}
+	}
+	var classifier *geancount.Classifier
+	if cCtx.Bool("learn") {
+		classifier = geancount.NewClassifier(ledger)
+	}
+	directives = rules.Categorize(directives, classifier)
+	duplicates := []geancount.Duplicate{}
+	if ledger != nil {
+		opts := geancount.DefaultDuplicateOptions
+		opts.Window = cCtx.Int("window")
+		duplicates = ledger.FindDuplicates(directives, opts)
+	}
+	if cCtx.Bool("drop-duplicates") {
+		dropped := map[int]bool{}
+		for _, d := range duplicates {
+			dropped[d.Index()] = true
+		}
+		kept := []geancount.Directive{}
+		for i, d := range directives {
+			if !dropped[i] {
+				kept = append(kept, d)
+			}
+		}
+		directives, duplicates = kept, nil
+	}
+	if err := geancount.WriteImported(os.Stdout, directives, duplicates, geancount.DefaultFormatOptions); err != nil {
+		return err
+	}
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// importCommand creates subcommand of import with a config
+func importCommand(name string, file string, usage string, action cli.ActionFunc, aliases ...string) *cli.Command {
+	return &cli.Command{
+		Name:    name,
+		Aliases: aliases,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "config",
+				Aliases:  []string{"c"},
+				Usage:    "YAML file with accounts of the statements",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "ledger",
+				Aliases: []string{"l"},
+				Usage:   "Ledger file, imported transactions already in it are commented out",
+			},
+			&cli.IntFlag{
+				Name:  "window",
+				Value: geancount.DefaultDuplicateOptions.Window,
+				Usage: "Number of days dates of duplicate transactions can differ",
+			},
+			&cli.BoolFlag{
+				Name:  "drop-duplicates",
+				Usage: "Drop duplicates instead of commenting them out",
+			},
+			&cli.StringFlag{
+				Name:    "rules",
+				Aliases: []string{"r"},
+				Usage:   "YAML file with rules of categorization of transactions",
+			},
+			&cli.BoolFlag{
+				Name:  "learn",
+				Usage: "Suggest contra accounts of transactions without a matching rule by transactions of the ledger",
+			},
+		},
+		ArgsUsage: file + "...",
+		Usage:     usage,
+		Action:    action,
+	}
+}
+
+// withoutConfig removes the config flag of an import command converting files which describe their accounts
+func withoutConfig(cmd *cli.Command) *cli.Command {
+	cmd.Flags = slices.DeleteFunc(cmd.Flags, func(f cli.Flag) bool {
+		return slices.Contains(f.Names(), "config")
+	})
+	return cmd
+}
+
+func importCSV(cCtx *cli.Context) error {
+	config, err := geancount.LoadCSVConfig(cCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	return importStatements(cCtx, func(r io.Reader, _ *geancount.Ledger) ([]geancount.Directive, error) {
+		transactions, err := geancount.ImportCSV(r, config)
+		directives := make([]geancount.Directive, len(transactions))
+		for i, t := range transactions {
+			directives[i] = t
+		}
+		return directives, err
+	})
+}
+
+func importOFX(cCtx *cli.Context) error {
+	config, err := geancount.LoadOFXConfig(cCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	// Sold units are reduced at the cost of lots held in the ledger
+	var holdings *geancount.LedgerState
+	return importStatements(cCtx, func(r io.Reader, ledger *geancount.Ledger) ([]geancount.Directive, error) {
+		if ledger != nil && holdings == nil {
+			ls, _ := ledger.GetState()
+			holdings = &ls
+		}
+		return geancount.ImportOFX(r, config, holdings)
+	})
+}
+
+func importCamt053(cCtx *cli.Context) error {
+	config, err := geancount.LoadBankConfig(cCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	return importStatements(cCtx, func(r io.Reader, _ *geancount.Ledger) ([]geancount.Directive, error) {
+		return geancount.ImportCamt053(r, config)
+	})
+}
+
+func importMT940(cCtx *cli.Context) error {
+	config, err := geancount.LoadBankConfig(cCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	return importStatements(cCtx, func(r io.Reader, _ *geancount.Ledger) ([]geancount.Directive, error) {
+		return geancount.ImportMT940(r, config)
+	})
+}
+
+func importQIF(cCtx *cli.Context) error {
+	config, err := geancount.LoadQIFConfig(cCtx.String("config"))
+	if err != nil {
+		return err
+	}
+	return importStatements(cCtx, func(r io.Reader, _ *geancount.Ledger) ([]geancount.Directive, error) {
+		return geancount.ImportQIF(r, config)
+	})
+}
+
+func importGnuCash(cCtx *cli.Context) error {
+	return importStatements(cCtx, func(r io.Reader, _ *geancount.Ledger) ([]geancount.Directive, error) {
+		return geancount.ImportGnuCash(r)
+	})
+}
+
+// convert converts a journal of other application with its includes and prints the ledger
+func convert(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return errors.New("one journal should be given")
+	}
+	if from := cCtx.String("from"); from != "ledger" && from != "hledger" {
+		return fmt.Errorf("can not convert from %s, only ledger and hledger are supported", from)
+	}
+	config := geancount.JournalConfig{}
+	if filename := cCtx.String("config"); filename != "" {
+		var err error
+		if config, err = geancount.LoadJournalConfig(filename); err != nil {
+			return err
+		}
+	}
+	directives, convertErr := geancount.ConvertJournal(cCtx.Args().First(), config)
+	if directives == nil && convertErr != nil {
+		return convertErr
+	}
+	if err := geancount.WriteDirectives(os.Stdout, directives, geancount.DefaultFormatOptions); err != nil {
+		return err
+	}
+	if convertErr != nil {
+		fmt.Fprintln(os.Stderr, convertErr)
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// reconcile compares an account with a bank statement and appends the balance of the statement
+// to the ledger if they match
+func reconcile(cCtx *cli.Context) error {
+	if cCtx.NArg() != 1 {
+		return errors.New("an account should be given")
+	}
+	end, err := time.Parse(time.DateOnly, cCtx.String("statement-end"))
+	if err != nil {
+		return fmt.Errorf("can not parse statement end %q", cCtx.String("statement-end"))
+	}
+	balance := cCtx.String("balance")
+	if currency := cCtx.String("currency"); currency != "" {
+		balance += " " + currency
+	}
+	statement, err := geancount.ParseAmount(balance)
+	if err != nil {
+		return err
+	}
+	ledger := geancount.NewLedger()
+	if err := ledger.LoadFile(cCtx.String("ledger")); err != nil {
+		return err
+	}
+	r, err := ledger.Reconcile(geancount.AccountName(cCtx.Args().First()), end, statement, cCtx.Bool("cleared-meta"))
+	if err != nil {
+		return err
+	}
+	if uncleared := r.Uncleared(); len(uncleared) > 0 {
+		fmt.Printf("Uncleared postings of %s until %s:\n", r.Account(), end.Format(time.DateOnly))
+		for _, u := range uncleared {
+			t := u.Transaction()
+			fmt.Printf("  %s %s %q %q  %s  %s:%d\n", t.Date().Format(time.DateOnly), t.Status(), t.Payee(),
+				t.Narration(), u.Posting().Amount(), t.FileName(), t.LineNum())
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Ledger balance     %s\n", r.Balance())
+	fmt.Printf("Uncleared          %s\n", r.UnclearedTotal())
+	fmt.Printf("Cleared balance    %s\n", r.ClearedBalance())
+	fmt.Printf("Statement balance  %s\n", r.Statement())
+	fmt.Printf("Difference         %s\n", r.Difference())
+	if !cCtx.Bool("append") {
+		if !r.Reconciled() {
+			return cli.Exit("", 1)
+		}
+		return nil
+	}
+	if err := r.AppendBalance(); err != nil {
+		return fmt.Errorf("balance is not appended: %w", err)
+	}
+	fmt.Printf("\nAppended balance on %s to %s\n", r.BalanceDirective().Date().Format(time.DateOnly), r.File())
+	return nil
+}
+
+// importPrices converts files of prices given in arguments and prints prices missing in the ledger
+func importPrices(cCtx *cli.Context) error {
+	opts := geancount.DefaultPriceOptions
+	opts.Delimiter = cCtx.String("delimiter")
+	opts.DateFormat = cCtx.String("date-format")
+	opts.DecimalSeparator = cCtx.String("decimal-separator")
+	opts.ECBPricesOfEUR = cCtx.Bool("ecb-prices-of-eur")
+	for _, commodity := range cCtx.StringSlice("commodity") {
+		opts.Commodities = append(opts.Commodities, geancount.Currency(commodity))
+	}
+	prices := []geancount.Price{}
+	errs := []error{}
+	for _, filename := range cCtx.Args().Slice() {
+		format := cCtx.String("format")
+		if format == "" && strings.EqualFold(filepath.Ext(filename), ".xml") {
+			format = "ecb"
+		}
+		var convert func(io.Reader, geancount.PriceOptions) ([]geancount.Price, error)
+		switch format {
+		case "", "csv":
+			convert = geancount.ImportPricesCSV
+		case "ecb":
+			convert = geancount.ImportECBRates
+		default:
+			return fmt.Errorf("unknown format %s, only csv and ecb are supported", format)
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		imported, err := convert(file, opts)
+		file.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+		}
+		prices = append(prices, imported...)
+	}
+	geancount.SortPrices(prices)
+	prices = geancount.UniquePrices(prices)
+	// A period with a price already in the ledger gets no other price
+	interval := cCtx.String("thin")
+	if interval != "" {
+		var err error
+		if prices, err = geancount.ThinPrices(prices, interval); err != nil {
+			return err
+		}
+	}
+	if filename := cCtx.String("ledger"); filename != "" {
+		ledger := geancount.NewLedger()
+		// Errors of the ledger are not important for import unless nothing is loaded
+		if err := ledger.LoadFile(filename); err != nil && len(ledger.Directives()) == 0 {
+			return err
+		}
+		ls, _ := ledger.GetState()
+		var err error
+		if prices, err = ls.MissingPrices(prices, interval); err != nil {
+			return err
+		}
+	}
+	directives := make([]geancount.Directive, len(prices))
+	for i, p := range prices {
+		directives[i] = p
+	}
+	if err := geancount.WriteDirectives(os.Stdout, directives, geancount.DefaultFormatOptions); err != nil {
+		return err
+	}
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+func formatFiles(cCtx *cli.Context) error {
+	opts := geancount.DefaultFormatOptions
+	opts.Indent = cCtx.Int("indent")
+	opts.CurrencyColumn = cCtx.Int("currency-column")
+	unformatted := []string{}
+	for _, filename := range cCtx.Args().Slice() {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		formatted := geancount.Format(src, opts)
+		if bytes.Equal(src, formatted) {
+			continue
+		}
+		unformatted = append(unformatted, filename)
+		switch {
+		case cCtx.Bool("diff"):
+			fmt.Print(unifiedDiff(filename, src, formatted))
+		case cCtx.Bool("check"):
+			fmt.Printf("%s is not formatted\n", filename)
+		default:
+			if err := os.WriteFile(filename, formatted, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	if (cCtx.Bool("check") || cCtx.Bool("diff")) && len(unformatted) > 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
 }
 
 // CreateCLI creates  CLI interface
@@ -82,6 +546,222 @@ func CreateCLI() {
 				Usage:  "Check ledger",
 				Action: checkLedger,
 			},
+			{
+				Name: "fmt",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Do not change files, exit with status 1 if any file is not formatted",
+					},
+					&cli.BoolFlag{
+						Name:  "diff",
+						Usage: "Do not change files, print diff and exit with status 1 if any file is not formatted",
+					},
+					&cli.IntFlag{
+						Name:  "indent",
+						Value: geancount.DefaultFormatOptions.Indent,
+						Usage: "Number of spaces used for indentation",
+					},
+					&cli.IntFlag{
+						Name:    "currency-column",
+						Aliases: []string{"c"},
+						Usage:   "Column where currencies are aligned, computed from the widest amount by default",
+					},
+				},
+				Usage:  "Formats files aligning amounts",
+				Action: formatFiles,
+			},
+			{
+				Name: "html",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "out",
+						Aliases:  []string{"o"},
+						Usage:    "Directory where the site is written",
+						Required: true,
+					},
+				},
+				Usage:  "Renders static HTML report",
+				Action: writeHTML,
+			},
+			{
+				Name: "export",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "sqlite",
+						Usage: "Export format, only sqlite is supported",
+					},
+					&cli.StringFlag{
+						Name:     "ledger",
+						Aliases:  []string{"l"},
+						Usage:    "Ledger file",
+						Required: true,
+					},
+				},
+				ArgsUsage: "out.db",
+				Usage:     "Exports ledger to a database",
+				Action:    export,
+			},
+			{
+				Name: "serve",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "addr",
+						Value: "localhost:8080",
+						Usage: "Address to listen on",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Value: time.Second,
+						Usage: "How often included files are checked for changes",
+					},
+				},
+				Usage:  "Serves HTML report and reloads it when files change",
+				Action: serve,
+			},
+			{
+				Name: "serve-metrics",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "addr",
+						Value: "localhost:9101",
+						Usage: "Address to listen on",
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Value: time.Second,
+						Usage: "How often included files are checked for changes",
+					},
+				},
+				Usage:  "Serves balances as OpenMetrics gauges and reloads them when files change",
+				Action: serveMetrics,
+			},
+			{
+				Name:  "import",
+				Usage: "Converts bank statements to transactions and prints them",
+				Subcommands: []*cli.Command{
+					importCommand("csv", "statement.csv", "Imports CSV statements, the config describes columns", importCSV),
+					importCommand("ofx", "statement.ofx", "Imports OFX 1.x and 2.x bank, credit card and investment statements",
+						importOFX, "qfx"),
+					importCommand("camt", "statement.xml", "Imports ISO 20022 camt.053 statements", importCamt053, "camt053"),
+					importCommand("mt940", "statement.sta", "Imports SWIFT MT940 statements", importMT940, "sta"),
+					importCommand("qif", "export.qif", "Converts a Quicken QIF file to a ledger with opening of accounts", importQIF),
+					withoutConfig(importCommand("gnucash", "book.gnucash",
+						"Converts an uncompressed or gzipped GnuCash XML book to a ledger with opening of accounts", importGnuCash)),
+				},
+			},
+			{
+				Name:  "convert",
+				Usage: "Converts a ledger-cli or hledger journal to a ledger with opening of accounts and prints it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "from",
+						Usage:    "Format of the journal: ledger or hledger",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "config",
+						Aliases: []string{"c"},
+						Usage:   "YAML file with currencies of commodities and renamed accounts",
+					},
+				},
+				ArgsUsage: "journal.dat",
+				Action:    convert,
+			},
+			{
+				Name:  "prices",
+				Usage: "Works with price history",
+				Subcommands: []*cli.Command{
+					{
+						Name: "import",
+						Usage: "Converts CSV files with date, commodity, price and currency columns and ECB reference rates XML " +
+							"to prices and prints prices missing in the ledger",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Format of the files: csv or ecb, ecb for .xml files and csv for others by default",
+							},
+							&cli.StringFlag{
+								Name:    "ledger",
+								Aliases: []string{"l"},
+								Usage:   "Ledger file, prices already in it are skipped",
+							},
+							&cli.StringFlag{
+								Name:  "thin",
+								Usage: "Keep only the last price of each weekly or monthly interval",
+							},
+							&cli.StringSliceFlag{
+								Name:  "commodity",
+								Usage: "Import only prices of the commodity, can be repeated",
+							},
+							&cli.StringFlag{
+								Name:  "delimiter",
+								Value: geancount.DefaultPriceOptions.Delimiter,
+								Usage: "Delimiter of fields in CSV files",
+							},
+							&cli.StringFlag{
+								Name:  "date-format",
+								Value: geancount.DefaultPriceOptions.DateFormat,
+								Usage: "Layout or strftime format like %d.%m.%Y of dates in CSV files",
+							},
+							&cli.StringFlag{
+								Name:  "decimal-separator",
+								Value: geancount.DefaultPriceOptions.DecimalSeparator,
+								Usage: "Decimal separator of prices in CSV files: . or ,",
+							},
+							&cli.BoolFlag{
+								Name:  "ecb-prices-of-eur",
+								Usage: "Keep ECB rates as prices of EUR instead of prices of currencies in EUR",
+							},
+						},
+						ArgsUsage: "prices.csv...",
+						Action:    importPrices,
+					},
+				},
+			},
+			{
+				Name:  "reconcile",
+				Usage: "Lists uncleared postings of the account until the end of a statement and compares its balances",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "ledger",
+						Aliases:  []string{"l"},
+						Usage:    "Ledger file",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "statement-end",
+						Usage:    "Last date of the statement like 2024-06-30",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "balance",
+						Usage:    "Closing balance of the statement like \"1234.56 EUR\" or a number with --currency",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "currency",
+						Usage: "Currency of the closing balance given as a number",
+					},
+					&cli.BoolFlag{
+						Name:  "cleared-meta",
+						Usage: "Postings without cleared metadata are uncleared too",
+					},
+					&cli.BoolFlag{
+						Name:  "append",
+						Usage: "Append the balance on the day after the statement end to the file of the account if it matches",
+					},
+				},
+				ArgsUsage: "Assets:Bank",
+				Action:    reconcile,
+			},
+			{
+				Name:      "lsp",
+				ArgsUsage: "[main file]",
+				Usage:     "Runs Language Server Protocol server on stdin and stdout, the first opened file is the main file by default",
+				Action:    serveLSP,
+			},
 		},
 	}
 
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestWithoutConfig(t *testing.T) {
+	cmd := importCommand("gnucash", "book.gnucash", "", nil)
+	cmd.Flags = append([]cli.Flag{&cli.BoolFlag{Name: "dry-run"}}, cmd.Flags...)
+	names := []string{}
+	for _, f := range withoutConfig(cmd).Flags {
+		names = append(names, f.Names()[0])
+	}
+	assert.False(t, slices.Contains(names, "config"))
+	assert.Equal(t, "dry-run", names[0])
+	assert.Contains(t, names, "rules")
+}
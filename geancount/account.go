@@ -30,6 +30,26 @@ func (a Account) String() string {
 	return string(a.name)
 }
 
+// Name returns name of the account
+func (a Account) Name() AccountName {
+	return a.name
+}
+
+// Currencies returns sorted currencies allowed in the account, empty if any currency is allowed
+func (a Account) Currencies() []Currency {
+	return sortedCurrencies(a.currencies)
+}
+
+// Opened returns dates when the account was opened
+func (a Account) Opened() []time.Time {
+	return slices.Clone(a.opened)
+}
+
+// Closed returns dates when the account was closed
+func (a Account) Closed() []time.Time {
+	return slices.Clone(a.closed)
+}
+
 // CurrencyAllowed checks if currency can be used in the account
 func (a Account) CurrencyAllowed(currency Currency) bool {
 	if a.currencies == nil || len(a.currencies) == 0 {
@@ -71,6 +91,34 @@ func (a Amount) String() string {
 	return fmt.Sprintf("%s %s", a.value, a.currency)
 }
 
+// NewAmount creates Amount
+func NewAmount(value decimal.Decimal, currency Currency) Amount {
+	return Amount{value: value, currency: currency}
+}
+
+// ParseAmount parses an amount like 1234.56 EUR
+func ParseAmount(s string) (Amount, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 || !(fields[1][0] >= 'A' && fields[1][0] <= 'Z') || !isCurrency(fields[1]) {
+		return Amount{}, fmt.Errorf("can not parse amount %q", s)
+	}
+	value, err := decimal.NewFromString(fields[0])
+	if err != nil {
+		return Amount{}, fmt.Errorf("can not parse amount %q", s)
+	}
+	return NewAmount(value, Currency(fields[1])), nil
+}
+
+// Value returns number part of the amount
+func (a Amount) Value() decimal.Decimal {
+	return a.value
+}
+
+// Currency returns currency of the amount
+func (a Amount) Currency() Currency {
+	return a.currency
+}
+
 // Negative return -a
 func (a Amount) Negative() Amount {
 	return Amount{
@@ -82,8 +130,19 @@ func (a Amount) Negative() Amount {
 // AccountOpen opens account and optionaly set currencies which can be used
 type AccountOpen struct {
 	directive
-	account    AccountName
-	currencies map[Currency]struct{}
+	account     AccountName
+	currencies  map[Currency]struct{}
+	accountSpan span
+}
+
+// Account returns name of the opened account
+func (a AccountOpen) Account() AccountName {
+	return a.account
+}
+
+// Currencies returns sorted currencies allowed in the account
+func (a AccountOpen) Currencies() []Currency {
+	return sortedCurrencies(a.currencies)
 }
 
 // Apply adds account to the LedgerState
@@ -94,7 +153,7 @@ func (a AccountOpen) Apply(ls *LedgerState) error {
 		ls.balances[a.account] = CurrenciesAmounts{}
 	} else {
 		if acc.IsOpen(a.Date()) {
-			return fmt.Errorf("Account %s is already open", a.account)
+			return newDiagnostic(CodeAccountAlreadyOpen, "Account %s is already open", a.account).at(a.accountSpan)
 		}
 		// If not first AccountOpen doesn't hove currencies we assume thier are the same
 		// Otherwise ensure that are equal to the Account currencies
@@ -108,7 +167,7 @@ func (a AccountOpen) Apply(ls *LedgerState) error {
 				newCurrencies = append(newCurrencies, c)
 			}
 			if len(newCurrencies) != len(actualCurrencies) {
-				return fmt.Errorf("Account %s can not change currencies", a.account)
+				return newDiagnostic(CodeAccountCurrencies, "Account %s can not change currencies", a.account).at(a.accountSpan)
 			}
 			slices.SortStableFunc(actualCurrencies, func(i, j Currency) int {
 				return cmp.Compare(string(i), string(j))
@@ -118,7 +177,7 @@ func (a AccountOpen) Apply(ls *LedgerState) error {
 			})
 			for i := range actualCurrencies {
 				if actualCurrencies[i] != newCurrencies[i] {
-					return fmt.Errorf("Account %s can not change currencies", a.account)
+					return newDiagnostic(CodeAccountCurrencies, "Account %s can not change currencies", a.account).at(a.accountSpan)
 				}
 			}
 		}
@@ -132,23 +191,38 @@ func (a AccountOpen) Apply(ls *LedgerState) error {
 // AccountClose closes the account
 type AccountClose struct {
 	directive
-	account AccountName
+	account     AccountName
+	accountSpan span
+}
+
+// Account returns name of the closed account
+func (a AccountClose) Account() AccountName {
+	return a.account
 }
 
 // Apply close account to the LedgerState
 func (a AccountClose) Apply(ls *LedgerState) error {
 	acc, ok := ls.accounts[a.account]
 	if !ok {
-		return fmt.Errorf("Account %s was not opened", a.account)
+		return newDiagnostic(CodeAccountNotOpen, "Account %s was not opened", a.account).at(a.accountSpan)
 	}
 	if acc.IsClosed(a.Date()) {
-		return fmt.Errorf("Account %s is already closed", a.account)
+		return newDiagnostic(CodeAccountAlreadyClosed, "Account %s is already closed", a.account).at(a.accountSpan)
 	}
 	acc.closed = append(acc.closed, a.Date())
 	ls.accounts[a.account] = acc
 	return nil
 }
 
+func sortedCurrencies(currencies map[Currency]struct{}) []Currency {
+	sorted := make([]Currency, 0, len(currencies))
+	for c := range currencies {
+		sorted = append(sorted, c)
+	}
+	slices.Sort(sorted)
+	return sorted
+}
+
 func newAccountOpen(lg LineGroup, fileName string) (AccountOpen, error) {
 	line := lg.lines[0]
 	date, err := parseDate(line.tokens[0].text)
@@ -166,8 +240,9 @@ func newAccountOpen(lg LineGroup, fileName string) (AccountOpen, error) {
 			fileName: fileName,
 			order:    accountOpenOrder,
 		},
-		account:    AccountName(accountName),
-		currencies: map[Currency]struct{}{},
+		account:     AccountName(accountName),
+		currencies:  map[Currency]struct{}{},
+		accountSpan: tokenSpan(line, 2),
 	}
 	if len(line.tokens) == 4 {
 		for _, currName := range strings.Split(line.tokens[3].text, ";") {
@@ -197,7 +272,8 @@ func newAccountClose(lg LineGroup, fileName string) (AccountClose, error) {
 			fileName: fileName,
 			order:    accountCloseOrder,
 		},
-		account: AccountName(accountName),
+		account:     AccountName(accountName),
+		accountSpan: tokenSpan(line, 2),
 	}
 	return d, nil
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +38,13 @@ func TestIsOpenIsClosed(t *testing.T) {
 	assert.True(t, acc.IsClosed(time.Date(2000, time.January, 11, 0, 0, 0, 0, time.UTC)),
 		"After the last close is closed")
 }
+
+func TestParseAmount(t *testing.T) {
+	a, err := ParseAmount("1234.56 EUR")
+	assert.Nil(t, err)
+	assert.Equal(t, NewAmount(decimal.RequireFromString("1234.56"), "EUR"), a)
+	_, err = ParseAmount("1234.56")
+	assert.NotNil(t, err)
+	_, err = ParseAmount("EUR 12")
+	assert.NotNil(t, err)
+}
@@ -10,15 +10,39 @@ import (
 // Balance checks the amount of the account at the date
 type Balance struct {
 	directive
-	account AccountName
-	amount  Amount
+	account     AccountName
+	amount      Amount
+	accountSpan span
+	amountSpan  span
 }
 
 // Pad inserts transaction to to make Balance assert
 type Pad struct {
 	directive
-	account       AccountName
-	sourceAccount AccountName
+	account           AccountName
+	sourceAccount     AccountName
+	accountSpan       span
+	sourceAccountSpan span
+}
+
+// Account returns account which balance is checked
+func (b Balance) Account() AccountName {
+	return b.account
+}
+
+// Amount returns expected amount
+func (b Balance) Amount() Amount {
+	return b.amount
+}
+
+// Account returns account which is padded
+func (p Pad) Account() AccountName {
+	return p.account
+}
+
+// SourceAccount returns account where padding amount is taken from
+func (p Pad) SourceAccount() AccountName {
+	return p.sourceAccount
 }
 
 var defaultPrecision decimal.Decimal
@@ -31,7 +55,7 @@ func init() {
 func (b Balance) Apply(ls *LedgerState) error {
 	accountBalance, ok := ls.balances[b.account]
 	if !ok {
-		return fmt.Errorf("Balance of unknown account %s", b.account)
+		return newDiagnostic(CodeAccountNotOpen, "Balance of unknown account %s", b.account).at(b.accountSpan)
 	}
 	calculated, ok := accountBalance[b.amount.currency]
 	if !ok {
@@ -41,22 +65,34 @@ func (b Balance) Apply(ls *LedgerState) error {
 	diff := calculated.Sub(b.amount.value).Abs()
 	if diff.GreaterThanOrEqual(defaultPrecision) {
 		if acc.pad != nil {
+			padLocation := acc.pad.accountSpan.location(acc.pad.FileName())
 			transation, err := acc.pad.createTransaction(b, calculated)
 			if err != nil {
-				return err
+				return newDiagnostic(CodePadFailed, "%s", diagnosticMessage(err)).at(b.amountSpan).withRelated(padLocation, "pad is here")
 			}
 			err = transation.Apply(ls)
 			if err != nil {
-				return err
+				return newDiagnostic(CodePadFailed, "%s", diagnosticMessage(err)).at(b.amountSpan).withRelated(padLocation, "pad is here")
 			}
 			if !ls.balances[b.account][b.amount.currency].Equal(b.amount.value) {
-				return fmt.Errorf("Could not create pad transaction for %s", b.account)
+				return newDiagnostic(CodePadFailed, "Could not create pad transaction for %s", b.account).
+					at(b.amountSpan).withRelated(padLocation, "pad is here")
 			}
 			acc.pad = nil
 			ls.accounts[b.account] = acc
 			return nil
 		}
-		return fmt.Errorf("Balance of %s expected %s but calcultated %s", b.account, b.amount.value, calculated)
+		d := newDiagnostic(CodeBalanceMismatch, "Balance of %s expected %s but calcultated %s", b.account, b.amount.value, calculated).
+			at(b.amountSpan)
+		difference := formatAmount(Amount{b.amount.value.Sub(calculated), b.amount.currency})
+		if last, ok := ls.lastPostings[b.account]; !ok {
+			d.withHint("difference is %s; there are no postings to this account", difference)
+		} else if last.FileName == b.FileName() {
+			d.withHint("difference is %s; last posting to this account was on line %d", difference, last.Line)
+		} else {
+			d.withHint("difference is %s; last posting to this account was on %s", difference, last)
+		}
+		return d
 	}
 	return nil
 }
@@ -82,21 +118,22 @@ func (p Pad) createTransaction(balance Balance, calculated decimal.Decimal) (Tra
 func (p Pad) Apply(ls *LedgerState) error {
 	acc, ok := ls.accounts[p.account]
 	if !ok {
-		return fmt.Errorf("Padding of unknow account %s", p.account)
+		return newDiagnostic(CodeAccountNotOpen, "Padding of unknow account %s", p.account).at(p.accountSpan)
 	}
 	if acc.IsClosed(p.Date()) {
-		return fmt.Errorf("Account %s is closed", acc)
+		return newDiagnostic(CodeAccountClosed, "Account %s is closed", acc).at(p.accountSpan)
 	}
-	sourceAcc := ls.accounts[p.sourceAccount]
+	sourceAcc, ok := ls.accounts[p.sourceAccount]
 	if !ok {
-		return fmt.Errorf("Padding of unknow account %s", p.sourceAccount)
+		return newDiagnostic(CodeAccountNotOpen, "Padding of unknow account %s", p.sourceAccount).at(p.sourceAccountSpan)
 	}
 	if sourceAcc.IsClosed(p.Date()) {
-		return fmt.Errorf("Account %s is closed", sourceAcc)
+		return newDiagnostic(CodeAccountClosed, "Account %s is closed", sourceAcc).at(p.sourceAccountSpan)
 	}
 	var err error = nil
 	if acc.pad != nil {
-		err = fmt.Errorf("Unused Pad entry")
+		err = newDiagnostic(CodePadUnused, "Unused Pad entry").at(p.accountSpan).
+			withRelated(acc.pad.accountSpan.location(acc.pad.FileName()), "previous pad is not used")
 	}
 	// Attach pad to the account anyway
 	acc.pad = &p
@@ -126,8 +163,10 @@ func newBalance(lg LineGroup, fileName string) (Balance, error) {
 			fileName: fileName,
 			order:    balanceOrder,
 		},
-		account: AccountName(accountName),
-		amount:  amount,
+		account:     AccountName(accountName),
+		amount:      amount,
+		accountSpan: tokenSpan(line, 2),
+		amountSpan:  tokensSpan(line, 3, 4),
 	}
 	return d, nil
 }
@@ -150,8 +189,10 @@ func newPad(lg LineGroup, fileName string) (Pad, error) {
 			fileName: fileName,
 			order:    padOrder,
 		},
-		account:       AccountName(accountName),
-		sourceAccount: AccountName(sourceAccountName),
+		account:           AccountName(accountName),
+		sourceAccount:     AccountName(sourceAccountName),
+		accountSpan:       tokenSpan(line, 2),
+		sourceAccountSpan: tokenSpan(line, 3),
 	}
 	return d, nil
 }
@@ -1,6 +1,7 @@
 package geancount
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,3 +14,15 @@ func TestPad(t *testing.T) {
 	_, err := ledger.GetState()
 	assert.Nil(t, err)
 }
+
+func TestPadFailed(t *testing.T) {
+	src := "2024-01-01 open Assets:Bank EUR\n2024-01-01 open Equity:Opening USD\n\n" +
+		"2024-01-01 pad Assets:Bank Equity:Opening\n2024-01-02 balance Assets:Bank 100.00 EUR\n"
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(src)))
+	_, err := ledger.GetState()
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, CodePadFailed, diagnostics[0].Code)
+	assert.Equal(t, "Currency EUR can not be used in account Equity:Opening", diagnostics[0].Message)
+}
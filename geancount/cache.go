@@ -0,0 +1,169 @@
+package geancount
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+const defaultCheckpointInterval = 1000
+
+// Cache keeps parsed files and checkpoints of the state between loads of the same ledger.
+// A file is parsed again only if its content is changed and GetState replays directives
+// from the last checkpoint before the first changed directive.
+// Files are keyed by path so a Cache should be used with one file system.
+// Only one ledger at a time should use the cache
+type Cache struct {
+	// mu guards files which are accessed by concurrent loading of includes
+	mu    sync.Mutex
+	files map[string]cachedFile
+	// keys are keys of directives applied by the last GetState
+	keys        []directiveKey
+	checkpoints []stateCheckpoint
+	interval    int
+}
+
+type cachedFile struct {
+	hash   [32]byte
+	parsed *parsedFile
+}
+
+// directiveKey identifies a directive between loads. Parsed files are reused while their content
+// is not changed, so the same key means the same directive
+type directiveKey struct {
+	parsed  *parsedFile
+	lineNum int
+	order   int
+}
+
+// stateCheckpoint is a state before the directive with index is applied
+type stateCheckpoint struct {
+	index int
+	state LedgerState
+	errs  []error
+}
+
+// NewCache creates empty Cache
+func NewCache() *Cache {
+	return &Cache{files: map[string]cachedFile{}, interval: defaultCheckpointInterval}
+}
+
+// UseCache makes the ledger take parsed files and states from the cache and update it
+func (l *Ledger) UseCache(c *Cache) {
+	l.cache = c
+	l.parsed = map[string]*parsedFile{}
+}
+
+func (c *Cache) lookup(filename string, hash [32]byte) (*parsedFile, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.files[filename]
+	if !ok || f.hash != hash {
+		return nil, false
+	}
+	return f.parsed, true
+}
+
+func (c *Cache) store(filename string, hash [32]byte, parsed *parsedFile) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[filename] = cachedFile{hash: hash, parsed: parsed}
+}
+
+// prune removes files which are not loaded anymore
+func (c *Cache) prune(files []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for f := range c.files {
+		if !slices.Contains(files, f) {
+			delete(c.files, f)
+		}
+	}
+}
+
+// restore returns the latest checkpoint valid for directives with keys, checkpoints after it are dropped
+func (c *Cache) restore(keys []directiveKey) (int, LedgerState, []error) {
+	common := 0
+	for common < len(keys) && common < len(c.keys) && keys[common] == c.keys[common] {
+		common++
+	}
+	for len(c.checkpoints) > 0 && c.checkpoints[len(c.checkpoints)-1].index > common {
+		c.checkpoints = c.checkpoints[:len(c.checkpoints)-1]
+	}
+	c.keys = keys
+	if len(c.checkpoints) == 0 {
+		return 0, newLedgerState(), []error{}
+	}
+	last := c.checkpoints[len(c.checkpoints)-1]
+	return last.index, last.state.clone(), slices.Clone(last.errs)
+}
+
+// stateBefore returns the latest checkpoint before the directive with index if checkpoints are taken
+// for directives with keys, otherwise the initial state
+func (c *Cache) stateBefore(index int, keys []directiveKey) (int, LedgerState) {
+	if c == nil || !slices.Equal(keys, c.keys) {
+		return 0, newLedgerState()
+	}
+	for i := len(c.checkpoints) - 1; i >= 0; i-- {
+		if c.checkpoints[i].index <= index {
+			return c.checkpoints[i].index, c.checkpoints[i].state.clone()
+		}
+	}
+	return 0, newLedgerState()
+}
+
+// checkpoint saves the state if the directive with index should have a checkpoint
+func (c *Cache) checkpoint(index int, ls LedgerState, errs []error) {
+	if index == 0 || index%c.interval != 0 {
+		return
+	}
+	if n := len(c.checkpoints); n > 0 && c.checkpoints[n-1].index >= index {
+		return
+	}
+	c.checkpoints = append(c.checkpoints, stateCheckpoint{index: index, state: ls.clone(), errs: slices.Clone(errs)})
+}
+
+// directiveKeys returns keys of the sorted directives
+func (l *Ledger) directiveKeys() []directiveKey {
+	keys := make([]directiveKey, len(l.directives))
+	for i, d := range l.directives {
+		keys[i] = directiveKey{parsed: l.parsed[d.FileName()], lineNum: d.LineNum(), order: d.Order()}
+	}
+	return keys
+}
+
+// clone returns a copy of the state which can be changed without affecting ls.
+// Transactions are shared instead of copied so checkpoints take memory linear in their number
+func (ls LedgerState) clone() LedgerState {
+	c := newLedgerState()
+	for name, acc := range ls.accounts {
+		acc.opened = slices.Clone(acc.opened)
+		acc.closed = slices.Clone(acc.closed)
+		c.accounts[name] = acc
+	}
+	for name, amounts := range ls.balances {
+		c.balances[name] = maps.Clone(amounts)
+	}
+	for name, inventory := range ls.inventories {
+		c.inventories[name] = map[Currency][]Lot{}
+		for currency, lots := range inventory {
+			c.inventories[name][currency] = slices.Clone(lots)
+		}
+	}
+	for currency, prices := range ls.prices {
+		c.prices[currency] = slices.Clone(prices)
+	}
+	// Transactions are only appended, the capped capacity makes the first append to the copy reallocate them
+	c.transactions = ls.transactions[:len(ls.transactions):len(ls.transactions)]
+	c.lastPostings = maps.Clone(ls.lastPostings)
+	return c
+}
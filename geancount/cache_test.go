@@ -0,0 +1,111 @@
+package geancount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.bean")
+	first := filepath.Join(dir, "2000.bean")
+	second := filepath.Join(dir, "2001.bean")
+	os.WriteFile(main, []byte("include \"2000.bean\"\ninclude \"2001.bean\"\n"), 0o644)
+	os.WriteFile(first, []byte(`2000-01-01 open Assets:Bank
+2000-01-01 open Income:Job
+2000-01-02 * "Salary"
+  Assets:Bank 100 EUR
+  Income:Job
+2000-01-03 * "Salary"
+  Assets:Bank 100 EUR
+  Income:Job
+2000-01-04 balance Assets:Bank 200 EUR
+`), 0o644)
+	os.WriteFile(second, []byte(`2001-01-02 * "Salary"
+  Assets:Bank 100 EUR
+  Income:Job
+`), 0o644)
+
+	cache := NewCache()
+	cache.interval = 2
+	load := func() (*Ledger, LedgerState, error) {
+		ledger := NewLedger()
+		ledger.UseCache(cache)
+		assert.Nil(t, ledger.LoadFile(main))
+		ls, err := ledger.GetState()
+		return ledger, ls, err
+	}
+	ledger, ls, err := load()
+	assert.Nil(t, err)
+	assert.Equal(t, "300", ls.balances["Assets:Bank"]["EUR"].String())
+	assert.Equal(t, 3, len(cache.files))
+	assert.Equal(t, []int{2, 4}, checkpointIndexes(cache))
+	parsedFirst := ledger.parsed[first]
+	checkpoint := cache.checkpoints[1]
+
+	os.WriteFile(second, []byte(`2001-01-02 * "Salary"
+  Assets:Bank 50 EUR
+  Income:Job
+2001-01-03 balance Assets:Bank 300 EUR
+`), 0o644)
+	ledger, ls, err = load()
+	// Unchanged file is not parsed again and checkpoints before the change are kept
+	assert.Same(t, parsedFirst, ledger.parsed[first])
+	assert.Equal(t, []int{2, 4, 6}, checkpointIndexes(cache))
+	assert.Equal(t, fmt.Sprintf("%p", checkpoint.state.balances), fmt.Sprintf("%p", cache.checkpoints[1].state.balances))
+	assert.Equal(t, "250", ls.balances["Assets:Bank"]["EUR"].String())
+	assert.Equal(t, "-250", ls.balances["Income:Job"]["EUR"].String())
+	assert.Equal(t, 1, len(Diagnostics(err)))
+	assert.Equal(t, 3, len(ls.transactions))
+
+	uncached := NewLedger()
+	uncached.LoadFile(main)
+	expected, expectedErr := uncached.GetState()
+	assert.Equal(t, expected.balances, ls.balances)
+	assert.Equal(t, Diagnostics(expectedErr), Diagnostics(err))
+
+	// States at dates start from checkpoints and match states replayed from the first directive
+	for _, date := range []time.Time{time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2001, 1, 2, 0, 0, 0, 0, time.UTC)} {
+		assert.Equal(t, uncached.stateAt(date).balances, ledger.stateAt(date).balances)
+	}
+	start, _ := cache.stateBefore(6, ledger.directiveKeys())
+	assert.Equal(t, 6, start)
+	start, _ = cache.stateBefore(6, uncached.directiveKeys())
+	assert.Equal(t, 0, start)
+
+	os.WriteFile(first, []byte("2000-01-01 open Assets:Bank\n"), 0o644)
+	os.WriteFile(main, []byte("include \"2000.bean\"\n"), 0o644)
+	_, ls, _ = load()
+	assert.Equal(t, 0, len(ls.transactions))
+	assert.Equal(t, []int{}, checkpointIndexes(cache))
+	_, ok := cache.files[second]
+	assert.False(t, ok)
+}
+
+func checkpointIndexes(c *Cache) []int {
+	indexes := []int{}
+	for _, checkpoint := range c.checkpoints {
+		indexes = append(indexes, checkpoint.index)
+	}
+	return indexes
+}
+
+func TestLedgerStateCloneSharesTransactions(t *testing.T) {
+	ls := newLedgerState()
+	for i := range 3 {
+		ls.transactions = append(ls.transactions, NewTransaction(time.Time{}, "*", "", fmt.Sprint(i), nil))
+	}
+	c := ls.clone()
+	assert.Same(t, &ls.transactions[0], &c.transactions[0])
+
+	ls.transactions = append(ls.transactions, NewTransaction(time.Time{}, "*", "", "ls", nil))
+	c.transactions = append(c.transactions, NewTransaction(time.Time{}, "*", "", "clone", nil))
+	assert.Equal(t, "ls", ls.transactions[3].Narration())
+	assert.Equal(t, "clone", c.transactions[3].Narration())
+	assert.Equal(t, "2", c.transactions[2].Narration())
+}
@@ -0,0 +1,194 @@
+package geancount
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// camtDocument is a part of camt.053 bank to customer statement used for import.
+// Elements are matched by local names so all versions of the schema are read
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	Other    string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Amount         camtAmount  `xml:"Amt"`
+	Indicator      string      `xml:"CdtDbtInd"`
+	Status         camtStatus  `xml:"Sts"`
+	BookingDate    camtDate    `xml:"BookgDt"`
+	Reference      string      `xml:"AcctSvcrRef"`
+	EntryReference string      `xml:"NtryRef"`
+	Details        []camtTxDtl `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string      `xml:"AddtlNtryInf"`
+}
+
+// camtStatus is a code of the status, it is nested in Cd since version 08 of the schema
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtTxDtl struct {
+	Reference    string   `xml:"Refs>AcctSvcrRef"`
+	Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorParty  string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPrty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	Structured   []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	s := d.Date
+	if s == "" && len(d.DateTime) >= 10 {
+		s = d.DateTime[:10]
+	}
+	date, err := parseDate(s)
+	if err != nil {
+		return date, fmt.Errorf("can not parse date %q", s)
+	}
+	return date, nil
+}
+
+// signed returns the amount negative for debit
+func (a camtAmount) signed(indicator string) (Amount, error) {
+	value, err := decimal.NewFromString(strings.TrimSpace(a.Value))
+	if err != nil {
+		return Amount{}, fmt.Errorf("can not parse amount %q", a.Value)
+	}
+	if indicator == "DBIT" {
+		value = value.Neg()
+	}
+	return NewAmount(value, Currency(a.Currency)), nil
+}
+
+// ImportCamt053 converts booked entries of ISO 20022 camt.053 statements to transactions.
+// The closing booked balance is asserted on the day after the statement.
+// Entries which can not be converted are skipped and reported in the returned error
+func ImportCamt053(r io.Reader, config BankConfig) ([]Directive, error) {
+	doc := camtDocument{}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	directives := []Directive{}
+	errs := []error{}
+	for _, stmt := range doc.Statements {
+		id := stmt.IBAN
+		if id == "" {
+			id = stmt.Other
+		}
+		account := config.account(id)
+		for _, entry := range stmt.Entries {
+			if status := strings.TrimSpace(entry.Status.Value + entry.Status.Code); status != "" && status != "BOOK" {
+				continue
+			}
+			t, err := entry.transaction(config, account)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("entry %s: %w", entry.reference(), err))
+				continue
+			}
+			directives = append(directives, t)
+		}
+		for _, balance := range stmt.Balances {
+			if balance.Type != "CLBD" {
+				continue
+			}
+			date, err := balance.Date.parse()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("closing balance: %w", err))
+				continue
+			}
+			amount, err := balance.Amount.signed(balance.Indicator)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("closing balance: %w", err))
+				continue
+			}
+			directives = append(directives, NewBalance(date.AddDate(0, 0, 1), account, amount))
+		}
+	}
+	slices.SortStableFunc(directives, compareDirectives)
+	return directives, errors.Join(errs...)
+}
+
+// reference returns reference of the entry given by the bank
+func (e camtEntry) reference() string {
+	if e.Reference != "" {
+		return e.Reference
+	}
+	for _, d := range e.Details {
+		if d.Reference != "" {
+			return d.Reference
+		}
+	}
+	return e.EntryReference
+}
+
+// transaction converts the entry, counterparty is the creditor of debits and the debtor of credits
+func (e camtEntry) transaction(config BankConfig, account AccountName) (Transaction, error) {
+	date, err := e.BookingDate.parse()
+	if err != nil {
+		return Transaction{}, err
+	}
+	amount, err := e.Amount.signed(e.Indicator)
+	if err != nil {
+		return Transaction{}, err
+	}
+	payee := ""
+	info := []string{}
+	for _, d := range e.Details {
+		if payee == "" {
+			if e.Indicator == "DBIT" {
+				payee = cmp.Or(d.Creditor, d.CreditorPrty)
+			} else {
+				payee = cmp.Or(d.Debtor, d.DebtorParty)
+			}
+		}
+		info = append(info, d.Unstructured...)
+		if len(d.Unstructured) == 0 {
+			info = append(info, d.Structured...)
+		}
+	}
+	narration := strings.Join(strings.Fields(strings.Join(info, " ")), " ")
+	if narration == "" {
+		narration = strings.TrimSpace(e.AdditionalInfo)
+	}
+	posting := NewPosting(account, amount)
+	if ref := e.reference(); ref != "" {
+		posting = posting.WithMeta("bank_ref", ref)
+	}
+	postings := []Posting{posting, NewPosting(config.ContraAccount, Amount{})}
+	return NewTransaction(date, config.Flag, strings.TrimSpace(payee), narration, postings), nil
+}
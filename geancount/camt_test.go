@@ -0,0 +1,48 @@
+package geancount
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCamt053(t *testing.T) {
+	config, err := ParseBankConfig([]byte("account: Assets:Unknown\ncontra_account: Expenses:Unknown\n" +
+		"accounts: {DE89370400440532013000: Assets:Bank}\n"))
+	assert.Nil(t, err)
+	file, err := os.Open("testdata/camt053.xml")
+	assert.Nil(t, err)
+	defer file.Close()
+	directives, err := ImportCamt053(file, config)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(directives))
+
+	salary := directives[0].(Transaction)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), salary.Date())
+	assert.Equal(t, "ACME GmbH", salary.Payee())
+	assert.Equal(t, "RF18539007547034", salary.Narration())
+	assert.Equal(t, AccountName("Assets:Bank"), salary.Postings()[0].Account())
+	assert.Equal(t, map[string]string{"bank_ref": "REF-1"}, salary.Postings()[0].Meta())
+
+	power := directives[1].(Transaction)
+	assert.Equal(t, "Power & Light AG", power.Payee())
+	assert.Equal(t, "Invoice 4711 January", power.Narration())
+	assert.Equal(t, "-50.00 EUR", sourceAmount(power.Postings()[0].Amount()))
+	assert.Equal(t, map[string]string{"bank_ref": "REF-2"}, power.Postings()[0].Meta())
+
+	balance := directives[2].(Balance)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), balance.Date())
+	assert.Equal(t, "2950.00 EUR", sourceAmount(balance.Amount()))
+
+	ls := loadImported(t, append([]Directive{NewTransaction(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), "*", "", "Opening",
+		[]Posting{NewPosting("Assets:Bank", NewAmount(decimal.RequireFromString("1000.00"), "EUR")), NewPosting("Expenses:Unknown", Amount{})})},
+		directives...), "Assets:Bank", "Expenses:Unknown")
+	assert.Equal(t, "2950.00", ls.Balance("Assets:Bank")["EUR"].StringFixed(2))
+
+	_, err = ImportCamt053(strings.NewReader("<Document"), config)
+	assert.NotNil(t, err)
+}
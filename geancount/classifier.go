@@ -0,0 +1,121 @@
+package geancount
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// minSuggestionProbability is a probability of the account required to suggest it
+const minSuggestionProbability = 0.5
+
+// Classifier is a naive Bayes classifier of accounts by words of payees and narrations
+type Classifier struct {
+	// documents is a number of trained transactions with a posting to the account
+	documents map[AccountName]int
+	// words are numbers of words in transactions with a posting to the account
+	words      map[AccountName]map[string]int
+	totals     map[AccountName]int
+	vocabulary map[string]struct{}
+	total      int
+}
+
+// NewClassifier trains the classifier on transactions of the ledger
+func NewClassifier(l *Ledger) *Classifier {
+	c := &Classifier{
+		documents:  map[AccountName]int{},
+		words:      map[AccountName]map[string]int{},
+		totals:     map[AccountName]int{},
+		vocabulary: map[string]struct{}{},
+	}
+	for _, d := range l.directives {
+		if t, ok := d.(Transaction); ok {
+			c.Train(t)
+		}
+	}
+	return c
+}
+
+// Train adds words of the transaction to every account it has postings to
+func (c *Classifier) Train(t Transaction) {
+	words := transactionWords(t)
+	if len(words) == 0 {
+		return
+	}
+	c.total++
+	accounts := []AccountName{}
+	for _, p := range t.postings {
+		if !slices.Contains(accounts, p.account) {
+			accounts = append(accounts, p.account)
+		}
+	}
+	for _, account := range accounts {
+		c.documents[account]++
+		if c.words[account] == nil {
+			c.words[account] = map[string]int{}
+		}
+		for _, w := range words {
+			c.words[account][w]++
+			c.totals[account]++
+			c.vocabulary[w] = struct{}{}
+		}
+	}
+}
+
+// Suggest returns the most probable account of the blank posting of the transaction.
+// Accounts the transaction already has postings to are not suggested, nothing is suggested
+// for unknown words or if the best account is not probable enough
+func (c *Classifier) Suggest(t Transaction) (AccountName, bool) {
+	if !slices.ContainsFunc(t.postings, func(p Posting) bool { return p.amount.currency == "" }) {
+		return "", false
+	}
+	words := []string{}
+	for _, w := range transactionWords(t) {
+		if _, ok := c.vocabulary[w]; ok {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return "", false
+	}
+	scores := map[AccountName]float64{}
+	for account, documents := range c.documents {
+		if slices.ContainsFunc(t.postings, func(p Posting) bool { return p.account == account }) {
+			continue
+		}
+		// Laplace smoothing gives unseen words a small probability
+		score := math.Log(float64(documents) / float64(c.total))
+		for _, w := range words {
+			score += math.Log(float64(c.words[account][w]+1) / float64(c.totals[account]+len(c.vocabulary)))
+		}
+		scores[account] = score
+	}
+	var best AccountName
+	for account, score := range scores {
+		if best == "" || score > scores[best] || score == scores[best] && account < best {
+			best = account
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+	return best, 1/sum >= minSuggestionProbability
+}
+
+// transactionWords returns lowercase words of the payee and the narration, numbers are skipped
+func transactionWords(t Transaction) []string {
+	words := []string{}
+	for _, w := range strings.FieldsFunc(t.payee+" "+t.narration, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) > 1 && strings.ContainsFunc(w, unicode.IsLetter) {
+			words = append(words, strings.ToLower(w))
+		}
+	}
+	return words
+}
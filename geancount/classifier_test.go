@@ -0,0 +1,57 @@
+package geancount
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifier(t *testing.T) {
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(`2024-01-01 open Assets:Bank
+2024-01-01 open Expenses:Food
+2024-01-01 open Expenses:Transport
+2024-01-01 open Income:Job
+
+2024-01-02 * "Corner Cafe" "Coffee"
+  Expenses:Food  4.50 EUR
+  Assets:Bank
+
+2024-01-03 * "City Bakery" "Bread"
+  Expenses:Food  3.20 EUR
+  Assets:Bank
+
+2024-01-04 * "Metro" "Monthly ticket 01"
+  Expenses:Transport  49.00 EUR
+  Assets:Bank
+
+2024-01-05 * "ACME" "Salary"
+  Assets:Bank  1000.00 EUR
+  Income:Job
+`)))
+	classifier := NewClassifier(ledger)
+
+	bank := func(payee string, narration string) Transaction {
+		return NewTransaction(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "*", payee, narration, []Posting{
+			NewPosting("Assets:Bank", NewAmount(decimal.RequireFromString("-5"), "EUR")),
+			NewPosting("Expenses:Unknown", Amount{}),
+		})
+	}
+	account, ok := classifier.Suggest(bank("CORNER CAFE", ""))
+	assert.True(t, ok)
+	assert.Equal(t, AccountName("Expenses:Food"), account)
+
+	account, ok = classifier.Suggest(bank("Metro", "ticket 02"))
+	assert.True(t, ok)
+	assert.Equal(t, AccountName("Expenses:Transport"), account)
+
+	_, ok = classifier.Suggest(bank("Unknown shop", "12345"))
+	assert.False(t, ok)
+
+	rules := Rules{}
+	result := rules.Categorize([]Directive{bank("ACME", "Salary February")}, classifier)
+	assert.Equal(t, AccountName("Income:Job"), result[0].(Transaction).Postings()[1].Account())
+}
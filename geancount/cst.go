@@ -0,0 +1,385 @@
+package geancount
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SyntaxKind is a kind of SyntaxToken
+type SyntaxKind uint8
+
+// Kinds of SyntaxToken. Whitespace, newlines and comments are trivia,
+// they do not affect the meaning of the source but are kept for round-trip.
+// Words are classified while lexing, SyntaxWord is a keyword or a word of other kind
+const (
+	SyntaxWhitespace SyntaxKind = iota
+	SyntaxNewline
+	SyntaxComment
+	SyntaxWord
+	SyntaxString
+	SyntaxBrace
+	SyntaxDate
+	SyntaxNumber
+	SyntaxAccount
+	SyntaxCurrency
+	SyntaxFlag
+	SyntaxPunct
+)
+
+// SyntaxToken is a piece of the source, all tokens together contain every byte of the source
+type SyntaxToken struct {
+	text string
+	// Positions are int32 to keep tokens of large sources small
+	lineNum int32
+	column  int32
+	kind    SyntaxKind
+}
+
+// Kind returns kind of the token
+func (t *SyntaxToken) Kind() SyntaxKind {
+	return t.kind
+}
+
+// Text returns the source text of the token, strings include quotes
+func (t *SyntaxToken) Text() string {
+	return t.text
+}
+
+// LineNum returns line where the token starts
+func (t *SyntaxToken) LineNum() int {
+	return int(t.lineNum)
+}
+
+// Column returns column in runes where the token starts, the first column is 1
+func (t *SyntaxToken) Column() int {
+	return int(t.column)
+}
+
+// IsTrivia returns true for whitespace, newlines and comments
+func (t *SyntaxToken) IsTrivia() bool {
+	return t.kind == SyntaxWhitespace || t.kind == SyntaxNewline || t.kind == SyntaxComment
+}
+
+// IsTerminated returns false for a string without closing quote
+func (t *SyntaxToken) IsTerminated() bool {
+	if t.kind != SyntaxString {
+		return true
+	}
+	s := t.text
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return false
+	}
+	// The closing quote is escaped if it follows an odd number of backslashes
+	backslashes := 0
+	for i := len(s) - 2; i > 0 && s[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// unescaper removes escaping of backslashes and quotes in strings
+var unescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// Value returns the text of the token without quotes and escaping
+func (t *SyntaxToken) Value() string {
+	if t.kind != SyntaxString {
+		return t.text
+	}
+	s := t.text[1:]
+	if t.IsTerminated() {
+		s = s[:len(s)-1]
+	}
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return unescaper.Replace(s)
+}
+
+// SetText replaces the source text of the token. Positions of following tokens are not updated
+func (t *SyntaxToken) SetText(text string) {
+	t.text = text
+}
+
+// SyntaxLine is a sequence of tokens ending with a newline.
+// A line with multi-line string spans over several lines of the source
+type SyntaxLine struct {
+	tokens []*SyntaxToken
+}
+
+// Tokens returns all tokens of the line including trivia
+func (l *SyntaxLine) Tokens() []*SyntaxToken {
+	return l.tokens
+}
+
+// CodeTokens returns tokens of the line which are not trivia
+func (l *SyntaxLine) CodeTokens() []*SyntaxToken {
+	tokens := []*SyntaxToken{}
+	for _, t := range l.tokens {
+		if !t.IsTrivia() {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// LineNum returns line where the SyntaxLine starts
+func (l *SyntaxLine) LineNum() int {
+	return l.tokens[0].LineNum()
+}
+
+// IsIndented returns true if the line starts with whitespace
+func (l *SyntaxLine) IsIndented() bool {
+	return l.tokens[0].kind == SyntaxWhitespace
+}
+
+// Comment returns comment token of the line or nil
+func (l *SyntaxLine) Comment() *SyntaxToken {
+	for _, t := range l.tokens {
+		if t.kind == SyntaxComment {
+			return t
+		}
+	}
+	return nil
+}
+
+func (l *SyntaxLine) hasCode() bool {
+	for _, t := range l.tokens {
+		if !t.IsTrivia() {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *SyntaxLine) isCommentOnly() bool {
+	return !l.hasCode() && l.Comment() != nil
+}
+
+func (l *SyntaxLine) endsWithNewline() bool {
+	return l.tokens[len(l.tokens)-1].kind == SyntaxNewline
+}
+
+// lastLineNum returns the line of the source where SyntaxLine ends
+func (l *SyntaxLine) lastLineNum() int {
+	last := l.tokens[len(l.tokens)-1]
+	n := last.LineNum() + strings.Count(last.text, "\n")
+	if last.kind == SyntaxNewline {
+		n--
+	}
+	return n
+}
+
+func (l *SyntaxLine) writeTo(buf *bytes.Buffer) {
+	for _, t := range l.tokens {
+		buf.WriteString(t.text)
+	}
+}
+
+// nodeChunk is a number of nodes allocated at once
+const nodeChunk = 1024
+
+// SyntaxNode is a directive with its comments or a run of blank and comment lines between directives
+type SyntaxNode struct {
+	lines []*SyntaxLine
+	// leading is a number of comment lines attached before the directive
+	leading     int
+	isDirective bool
+}
+
+// IsDirective returns false for nodes containing only blank lines and comments
+func (n *SyntaxNode) IsDirective() bool {
+	return n.isDirective
+}
+
+// Lines returns all lines of the node including attached comments
+func (n *SyntaxNode) Lines() []*SyntaxLine {
+	return n.lines
+}
+
+// DirectiveLines returns lines of the directive without leading comments
+func (n *SyntaxNode) DirectiveLines() []*SyntaxLine {
+	return n.lines[n.leading:]
+}
+
+// LineNum returns line where the directive starts
+func (n *SyntaxNode) LineNum() int {
+	return n.lines[n.leading].LineNum()
+}
+
+// Bytes returns source of the node
+func (n *SyntaxNode) Bytes() []byte {
+	buf := bytes.Buffer{}
+	for _, l := range n.lines {
+		l.writeTo(&buf)
+	}
+	return buf.Bytes()
+}
+
+// SyntaxTree is a lossless representation of a source file, Bytes returns exactly the parsed source
+type SyntaxTree struct {
+	nodes []*SyntaxNode
+}
+
+// Nodes returns all nodes of the tree
+func (t *SyntaxTree) Nodes() []*SyntaxNode {
+	return t.nodes
+}
+
+// Directives returns nodes which contain directives
+func (t *SyntaxTree) Directives() []*SyntaxNode {
+	directives := []*SyntaxNode{}
+	for _, n := range t.nodes {
+		if n.isDirective {
+			directives = append(directives, n)
+		}
+	}
+	return directives
+}
+
+// Bytes returns the source of the tree
+func (t *SyntaxTree) Bytes() []byte {
+	buf := bytes.Buffer{}
+	for _, n := range t.nodes {
+		for _, l := range n.lines {
+			l.writeTo(&buf)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (t *SyntaxTree) lines() []*SyntaxLine {
+	n := 0
+	for _, node := range t.nodes {
+		n += len(node.lines)
+	}
+	lines := make([]*SyntaxLine, 0, n)
+	for _, n := range t.nodes {
+		lines = append(lines, n.lines...)
+	}
+	return lines
+}
+
+// InsertAfter parses src and inserts its nodes after the node, if node is nil src is added to the end
+func (t *SyntaxTree) InsertAfter(node *SyntaxNode, src []byte) {
+	i := len(t.nodes)
+	if node != nil {
+		i = t.indexOf(node) + 1
+	}
+	t.insert(i, src)
+}
+
+// InsertBefore parses src and inserts its nodes before the node
+func (t *SyntaxTree) InsertBefore(node *SyntaxNode, src []byte) {
+	t.insert(t.indexOf(node), src)
+}
+
+// Remove removes the node from the tree
+func (t *SyntaxTree) Remove(node *SyntaxNode) {
+	if i := t.indexOf(node); i != -1 {
+		t.nodes = append(t.nodes[:i], t.nodes[i+1:]...)
+	}
+}
+
+func (t *SyntaxTree) indexOf(node *SyntaxNode) int {
+	for i, n := range t.nodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *SyntaxTree) insert(i int, src []byte) {
+	if i < 0 {
+		return
+	}
+	// Inserted source should start on a new line
+	if i > 0 {
+		prev := t.nodes[i-1]
+		lastLine := prev.lines[len(prev.lines)-1]
+		if !lastLine.endsWithNewline() {
+			lastLine.tokens = append(lastLine.tokens, &SyntaxToken{kind: SyntaxNewline, text: "\n", lineNum: int32(lastLine.lastLineNum())})
+		}
+	}
+	if len(src) > 0 && src[len(src)-1] != '\n' && i < len(t.nodes) {
+		src = append(append([]byte{}, src...), '\n')
+	}
+	inserted := ParseSyntaxTree(src).nodes
+	t.nodes = append(t.nodes[:i], append(inserted, t.nodes[i:]...)...)
+}
+
+// ParseSyntaxTree splits source into lossless tree of nodes, lines and tokens
+func ParseSyntaxTree(src []byte) *SyntaxTree {
+	return &SyntaxTree{nodes: groupSyntaxLines(splitSyntaxLines(lexSource(src)))}
+}
+
+// splitSyntaxLines splits tokens by newlines, the newline token ends the line.
+// Tokens of lines share the array of tokens, capacity is limited so appending to a line is safe
+func splitSyntaxLines(tokens []*SyntaxToken) []*SyntaxLine {
+	n := 0
+	for _, t := range tokens {
+		if t.kind == SyntaxNewline {
+			n++
+		}
+	}
+	slab := make([]SyntaxLine, 0, n+1)
+	lines := make([]*SyntaxLine, 0, n+1)
+	start := 0
+	for i, t := range tokens {
+		if t.kind == SyntaxNewline {
+			slab = append(slab, SyntaxLine{tokens: tokens[start : i+1 : i+1]})
+			lines = append(lines, &slab[len(slab)-1])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		slab = append(slab, SyntaxLine{tokens: tokens[start:len(tokens):len(tokens)]})
+		lines = append(lines, &slab[len(slab)-1])
+	}
+	return lines
+}
+
+// groupSyntaxLines groups lines into directives. Comment lines directly before a directive are
+// attached to it, indented lines and comments following the directive belong to it.
+// Lines of a node are a part of lines with limited capacity
+func groupSyntaxLines(lines []*SyntaxLine) []*SyntaxNode {
+	nodes := []*SyntaxNode{}
+	chunk := []SyntaxNode{}
+	newNode := func(from, to, leading int, isDirective bool) *SyntaxNode {
+		if len(chunk) == cap(chunk) {
+			chunk = make([]SyntaxNode, 0, nodeChunk)
+		}
+		chunk = append(chunk, SyntaxNode{lines: lines[from:to:to], leading: leading, isDirective: isDirective})
+		node := &chunk[len(chunk)-1]
+		nodes = append(nodes, node)
+		return node
+	}
+	var current *SyntaxNode
+	currentFrom := 0
+	// Lines from pendingFrom up to the current line do not belong to any node yet
+	pendingFrom := 0
+	for i, l := range lines {
+		switch {
+		case l.hasCode() && !l.IsIndented():
+			leading := 0
+			for leading < i-pendingFrom && lines[i-1-leading].isCommentOnly() && !lines[i-1-leading].IsIndented() {
+				leading++
+			}
+			if i-leading > pendingFrom {
+				newNode(pendingFrom, i-leading, 0, false)
+			}
+			currentFrom = i - leading
+			current = newNode(currentFrom, i+1, leading, true)
+			pendingFrom = i + 1
+		case current != nil && pendingFrom == i && l.IsIndented() && (l.hasCode() || l.isCommentOnly()):
+			current.lines = lines[currentFrom : i+1 : i+1]
+			pendingFrom = i + 1
+		default:
+			current = nil
+		}
+	}
+	if len(lines) > pendingFrom {
+		newNode(pendingFrom, len(lines), 0, false)
+	}
+	return nodes
+}
@@ -0,0 +1,79 @@
+package geancount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntaxTreeRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.bean")
+	assert.Nil(t, err)
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		assert.Nil(t, err)
+		assert.Equal(t, string(src), string(ParseSyntaxTree(src).Bytes()), f)
+	}
+	for _, src := range []string{
+		"",
+		"\n\n",
+		"2000-01-01 open Assets:Bank ; no final newline",
+		"2000-01-01 open Assets:Bank\r\n\r\n",
+		"2000-01-02 * \"Multi\nline \\\" narration\" ; ok\n  Assets:Bank  1,000.00 EUR {2 USD}\n",
+		"2000-01-02 * \"unterminated\n  Assets:Bank 1 EUR\n",
+		"\t ; indented comment\n2000-01-01 open Assets:Счёт\n",
+	} {
+		assert.Equal(t, src, string(ParseSyntaxTree([]byte(src)).Bytes()))
+	}
+}
+
+func TestSyntaxTreeNodes(t *testing.T) {
+	src := `; header
+
+; about bank
+; more about bank
+2000-01-01 open Assets:Bank
+2000-01-02 * "Shop"
+  Assets:Bank  -1 EUR
+  ; inside
+  Expenses:Food
+
+; footer
+`
+	tree := ParseSyntaxTree([]byte(src))
+	nodes := tree.Nodes()
+	assert.Equal(t, 4, len(nodes))
+	assert.False(t, nodes[0].IsDirective())
+	assert.Equal(t, "; header\n\n", string(nodes[0].Bytes()))
+	assert.True(t, nodes[1].IsDirective())
+	assert.Equal(t, 5, nodes[1].LineNum())
+	assert.Equal(t, "; about bank\n; more about bank\n2000-01-01 open Assets:Bank\n", string(nodes[1].Bytes()))
+	assert.Equal(t, 1, len(nodes[1].DirectiveLines()))
+	assert.Equal(t, 4, len(nodes[2].Lines()))
+	assert.Equal(t, "\n; footer\n", string(nodes[3].Bytes()))
+	assert.Equal(t, 2, len(tree.Directives()))
+
+	tokens := nodes[2].Lines()[0].CodeTokens()
+	assert.Equal(t, 3, len(tokens))
+	assert.Equal(t, SyntaxString, tokens[2].Kind())
+	assert.Equal(t, "Shop", tokens[2].Value())
+	assert.Equal(t, 14, tokens[2].Column())
+	assert.Equal(t, 6, tokens[2].LineNum())
+}
+
+func TestSyntaxTreeEdit(t *testing.T) {
+	src := "2000-01-01 open  Assets:Bank   ; bank\n\n2000-01-02 *  \"Shop\"\n  Assets:Bank    -1,000.00 EUR\n  Expenses:Food\n"
+	tree := ParseSyntaxTree([]byte(src))
+	txn := tree.Directives()[1]
+	amount := txn.Lines()[1].CodeTokens()[1]
+	assert.Equal(t, "-1,000.00", amount.Text())
+	amount.SetText("-2,000.00")
+	assert.Equal(t, "2000-01-01 open  Assets:Bank   ; bank\n\n2000-01-02 *  \"Shop\"\n  Assets:Bank    -2,000.00 EUR\n  Expenses:Food\n", string(tree.Bytes()))
+
+	tree.InsertAfter(tree.Directives()[0], []byte("2000-01-01 open Expenses:Food"))
+	tree.InsertAfter(nil, []byte("\n2000-01-03 close Assets:Bank\n"))
+	tree.Remove(txn)
+	assert.Equal(t, "2000-01-01 open  Assets:Bank   ; bank\n2000-01-01 open Expenses:Food\n\n\n2000-01-03 close Assets:Bank\n", string(tree.Bytes()))
+}
@@ -0,0 +1,268 @@
+package geancount
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// CSVConfig describes how rows of a bank statement in CSV are converted to transactions
+type CSVConfig struct {
+	ImportConfig `yaml:",inline"`
+	// Currency is used if there is no currency column
+	Currency Currency `yaml:"currency"`
+	// Delimiter separates fields, comma by default
+	Delimiter string `yaml:"delimiter"`
+	// SkipRows is a number of rows before the header
+	SkipRows int `yaml:"skip_rows"`
+	// NoHeader is set if there is no header, columns are referenced by zero based indexes then
+	NoHeader bool `yaml:"no_header"`
+	// DateFormat is a layout of time.Parse or strftime format like %d.%m.%Y, 2006-01-02 by default
+	DateFormat string `yaml:"date_format"`
+	// DecimalSeparator is . or , the other one is a thousands separator
+	DecimalSeparator string `yaml:"decimal_separator"`
+	// Negate inverts signs of amounts, useful for statements of credit cards
+	Negate  bool       `yaml:"negate"`
+	Columns CSVColumns `yaml:"columns"`
+}
+
+// CSVColumns are names of columns in the header or their zero based indexes.
+// Either Amount or Debit and Credit are used, debit is money going out of the account
+type CSVColumns struct {
+	Date        string `yaml:"date"`
+	Amount      string `yaml:"amount"`
+	Debit       string `yaml:"debit"`
+	Credit      string `yaml:"credit"`
+	Payee       string `yaml:"payee"`
+	Description string `yaml:"description"`
+	Currency    string `yaml:"currency"`
+}
+
+// LoadCSVConfig reads the config from YAML file
+func LoadCSVConfig(filename string) (CSVConfig, error) {
+	return loadImportConfig(filename, ParseCSVConfig)
+}
+
+// ParseCSVConfig parses YAML config, fills defaults and checks it
+func ParseCSVConfig(data []byte) (CSVConfig, error) {
+	config := CSVConfig{ImportConfig: defaultImportConfig, Delimiter: ",", DateFormat: "2006-01-02", DecimalSeparator: "."}
+	if err := parseImportConfig(data, &config, &config.ImportConfig); err != nil {
+		return config, err
+	}
+	switch {
+	case config.Currency == "" && config.Columns.Currency == "":
+		return config, errors.New("currency or currency column should be set")
+	case config.Columns.Date == "":
+		return config, errors.New("date column should be set")
+	case config.Columns.Amount == "" && config.Columns.Debit == "" && config.Columns.Credit == "":
+		return config, errors.New("amount or debit and credit columns should be set")
+	case config.Columns.Amount != "" && (config.Columns.Debit != "" || config.Columns.Credit != ""):
+		return config, errors.New("amount can not be used with debit and credit columns")
+	case utf8.RuneCountInString(config.Delimiter) != 1:
+		return config, fmt.Errorf("delimiter %q should be one character", config.Delimiter)
+	case config.DecimalSeparator != "." && config.DecimalSeparator != ",":
+		return config, fmt.Errorf("decimal_separator %q should be . or ,", config.DecimalSeparator)
+	}
+	return config, nil
+}
+
+// strftimeLayouts are layouts of time.Parse for strftime directives
+var strftimeLayouts = strings.NewReplacer(
+	"%Y", "2006", "%y", "06", "%m", "01", "%d", "02", "%e", "_2", "%b", "Jan", "%B", "January",
+	"%H", "15", "%M", "04", "%S", "05", "%%", "%",
+)
+
+// dateLayout converts strftime format to a layout of time.Parse, other formats are already layouts
+func dateLayout(format string) string {
+	if !strings.Contains(format, "%") {
+		return format
+	}
+	return strftimeLayouts.Replace(format)
+}
+
+// csvColumnIndexes are indexes of configured columns, -1 if a column is not used
+type csvColumnIndexes struct {
+	date, amount, debit, credit, payee, description, currency int
+}
+
+// ImportCSV converts rows of the statement to transactions sorted by date.
+// Rows which can not be converted are skipped and reported in the returned error
+func ImportCSV(r io.Reader, config CSVConfig) ([]Transaction, error) {
+	config.DateFormat = dateLayout(config.DateFormat)
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(config.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for range config.SkipRows {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("can not skip rows: %w", err)
+		}
+	}
+	var header []string
+	if !config.NoHeader {
+		row, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can not read header: %w", err)
+		}
+		header = row
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+	}
+	indexes, err := config.Columns.indexes(header)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := []Transaction{}
+	errs := []error{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return transactions, err
+		}
+		if isBlankRow(row) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		t, err := config.transaction(row, indexes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		transactions = append(transactions, t)
+	}
+	slices.SortStableFunc(transactions, func(a, b Transaction) int {
+		return a.Date().Compare(b.Date())
+	})
+	return transactions, errors.Join(errs...)
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// indexes finds indexes of the columns in the header
+func (c CSVColumns) indexes(header []string) (csvColumnIndexes, error) {
+	errs := []error{}
+	index := func(column string) int {
+		if column == "" {
+			return -1
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				return i
+			}
+		}
+		i, err := strconv.Atoi(column)
+		if err != nil || i < 0 {
+			errs = append(errs, fmt.Errorf("column %q is not found", column))
+			return -1
+		}
+		return i
+	}
+	indexes := csvColumnIndexes{
+		date:        index(c.Date),
+		amount:      index(c.Amount),
+		debit:       index(c.Debit),
+		credit:      index(c.Credit),
+		payee:       index(c.Payee),
+		description: index(c.Description),
+		currency:    index(c.Currency),
+	}
+	return indexes, errors.Join(errs...)
+}
+
+// transaction converts the row to a transaction
+func (c CSVConfig) transaction(row []string, indexes csvColumnIndexes) (Transaction, error) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	date, err := time.Parse(c.DateFormat, cell(indexes.date))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("can not parse date %q", cell(indexes.date))
+	}
+	var value decimal.Decimal
+	if indexes.amount >= 0 {
+		value, err = c.parseNumber(cell(indexes.amount))
+		if err != nil {
+			return Transaction{}, err
+		}
+	} else {
+		debit, err := c.parseNumber(cell(indexes.debit))
+		if err != nil {
+			return Transaction{}, err
+		}
+		credit, err := c.parseNumber(cell(indexes.credit))
+		if err != nil {
+			return Transaction{}, err
+		}
+		value = credit.Abs().Sub(debit.Abs())
+	}
+	if c.Negate {
+		value = value.Neg()
+	}
+	currency := c.Currency
+	if s := cell(indexes.currency); s != "" {
+		currency = Currency(strings.ToUpper(s))
+	}
+	if !isCurrency(string(currency)) {
+		return Transaction{}, fmt.Errorf("%q is not a valid currency", currency)
+	}
+	postings := []Posting{
+		NewPosting(c.Account, NewAmount(value, currency)),
+		NewPosting(c.ContraAccount, Amount{}),
+	}
+	return NewTransaction(date, c.Flag, cell(indexes.payee), cell(indexes.description), postings), nil
+}
+
+// parseNumber parses number with separators set in the config.
+// Empty string is zero, negative numbers can be in parentheses or have trailing minus
+func (c CSVConfig) parseNumber(s string) (decimal.Decimal, error) {
+	original := s
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+		negative = true
+	}
+	if strings.HasSuffix(s, "-") {
+		s = s[:len(s)-1]
+		negative = true
+	}
+	thousands := ","
+	if c.DecimalSeparator == "," {
+		thousands = "."
+	}
+	s = strings.NewReplacer(thousands, "", " ", "", "\u00a0", "", "'", "").Replace(s)
+	s = strings.Replace(s, c.DecimalSeparator, ".", 1)
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return value, fmt.Errorf("can not parse amount %q", original)
+	}
+	if negative {
+		value = value.Neg()
+	}
+	return value, nil
+}
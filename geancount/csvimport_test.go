@@ -0,0 +1,97 @@
+package geancount
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVConfig(t *testing.T) {
+	config, err := ParseCSVConfig([]byte("account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: EUR\n" +
+		"columns:\n  date: 0\n  amount: Amount\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, ",", config.Delimiter)
+	assert.Equal(t, "*", config.Flag)
+	assert.Equal(t, "0", config.Columns.Date)
+
+	for _, src := range []string{
+		"account: Bank\ncontra_account: Expenses:Unknown\ncurrency: EUR\ncolumns: {date: Date, amount: Amount}\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncolumns: {date: Date, amount: Amount}\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: EUR\ncolumns: {date: Date}\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: EUR\ncolumns: {date: Date, amount: A, debit: D}\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: EUR\ncolumns: {date: Date, amount: A}\nunknown: 1\n",
+	} {
+		_, err := ParseCSVConfig([]byte(src))
+		assert.NotNil(t, err, src)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	config, err := ParseCSVConfig([]byte(`
+account: Assets:Bank:Checking
+contra_account: Expenses:Unknown
+currency: EUR
+delimiter: ";"
+skip_rows: 1
+date_format: "%d.%m.%Y"
+decimal_separator: ","
+columns:
+  date: Buchungstag
+  debit: Soll
+  credit: Haben
+  payee: Empfänger
+  description: Verwendungszweck
+`))
+	assert.Nil(t, err)
+	statement := "\ufeffKontoauszug 2024\n" +
+		"Buchungstag;Empfänger;Verwendungszweck;Soll;Haben\n" +
+		"03.01.2024;\"Shop \"\"Best\"\"\";Groceries;1.234,50;\n" +
+		"02.01.2024;Employer;Salary;;3.000,00\n" +
+		";;;;\n" +
+		"2024-01-04;Shop;Broken;1,00;\n"
+	transactions, err := ImportCSV(strings.NewReader(statement), config)
+	assert.Equal(t, "line 6: can not parse date \"2024-01-04\"", err.Error())
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), transactions[0].Date())
+	assert.Equal(t, "Employer", transactions[0].Payee())
+	assert.Equal(t, "Salary", transactions[0].Narration())
+	assert.True(t, transactions[0].Postings()[0].Amount().Value().Equal(decimal.NewFromInt(3000)))
+	assert.Equal(t, `Shop "Best"`, transactions[1].Payee())
+	assert.Equal(t, "-1234.50", transactions[1].Postings()[0].Amount().Value().StringFixed(2))
+	assert.Equal(t, AccountName("Expenses:Unknown"), transactions[1].Postings()[1].Account())
+
+	directives := []Directive{
+		NewAccountOpen(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "Assets:Bank:Checking", nil),
+		NewAccountOpen(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "Expenses:Unknown", nil),
+	}
+	for _, t := range transactions {
+		directives = append(directives, t)
+	}
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives, DefaultFormatOptions))
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("imported.bean", &out))
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+	assert.Equal(t, "1765.50", ls.Balance("Assets:Bank:Checking")["EUR"].StringFixed(2))
+	assert.Equal(t, `Shop "Best"`, DirectivesOf[Transaction](ledger)[1].Payee())
+}
+
+func TestImportCSVWithoutHeader(t *testing.T) {
+	config, err := ParseCSVConfig([]byte("account: Liabilities:Card\ncontra_account: Expenses:Unknown\nno_header: true\n" +
+		"negate: true\ncolumns: {date: 0, description: 1, amount: 2, currency: 3}\n"))
+	assert.Nil(t, err)
+	transactions, err := ImportCSV(strings.NewReader("2024-01-02,Coffee,\"1,200.00\",usd\n2024-01-03,Refund,(5.00),USD\n"), config)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, "-1200.00 USD", sourceAmount(transactions[0].Postings()[0].Amount()))
+	assert.Equal(t, "5.00 USD", sourceAmount(transactions[1].Postings()[0].Amount()))
+
+	config.Columns.Payee = "Payee"
+	_, err = ImportCSV(strings.NewReader("2024-01-02,Coffee,1.00,USD\n"), config)
+	assert.Equal(t, "column \"Payee\" is not found", err.Error())
+}
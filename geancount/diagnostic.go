@@ -0,0 +1,214 @@
+package geancount
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Severity is a level of a Diagnostic
+type Severity int
+
+// Severities of diagnostics, only errors make the ledger invalid
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Codes of diagnostics. They are stable and can be used to filter diagnostics
+const (
+	CodeIO                    = "E-IO"
+	CodeParse                 = "E-PARSE"
+	CodeInclude               = "E-INCLUDE"
+	CodeIncludeCycle          = "E-INCLUDE-CYCLE"
+	CodeDuplicateInclude      = "E-DUPLICATE-INCLUDE"
+	CodeUnknownOption         = "W-UNKNOWN-OPTION"
+	CodeAccountAlreadyOpen    = "E-ACCOUNT-ALREADY-OPEN"
+	CodeAccountAlreadyClosed  = "E-ACCOUNT-ALREADY-CLOSED"
+	CodeAccountNotOpen        = "E-ACCOUNT-NOT-OPEN"
+	CodeAccountClosed         = "E-ACCOUNT-CLOSED"
+	CodeAccountCurrencies     = "E-ACCOUNT-CURRENCIES"
+	CodeCurrencyNotAllowed    = "E-CURRENCY-NOT-ALLOWED"
+	CodeBalanceMismatch       = "E-BALANCE-MISMATCH"
+	CodePadFailed             = "E-PAD-FAILED"
+	CodePadUnused             = "E-PAD-UNUSED"
+	CodeMultipleBlankPostings = "E-MULTIPLE-BLANK-POSTINGS"
+	CodeNoLots                = "E-NO-LOTS"
+	CodeLotsNotMatched        = "E-LOTS-NOT-MATCHED"
+	CodeDocumentMissing       = "E-DOCUMENT-MISSING"
+	CodeDocumentOrphan        = "W-DOCUMENT-ORPHAN"
+	CodeUnknown               = "E-UNKNOWN"
+)
+
+// Location is a place in a source file. Columns are counted in runes starting from 1,
+// they are 0 if the location is the whole line
+type Location struct {
+	FileName  string
+	Line      int
+	Column    int
+	EndColumn int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%02d", l.FileName, l.Line)
+}
+
+// RelatedLocation points to another place which helps to understand a Diagnostic
+type RelatedLocation struct {
+	Location
+	Message string
+}
+
+// Diagnostic is a problem found when the ledger is loaded or its state is computed
+type Diagnostic struct {
+	Location
+	Severity Severity
+	Code     string
+	Message  string
+	Hint     string
+	Related  []RelatedLocation
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s %s", d.Location, d.Message)
+}
+
+// span is a position of a token inside a file
+type span struct {
+	line      int
+	column    int
+	endColumn int
+}
+
+func tokenSpan(line Line, i int) span {
+	t := line.tokens[i]
+	return span{line: line.lineNum, column: int(t.column), endColumn: int(t.endColumn)}
+}
+
+// tokensSpan returns span from the token from to the token to inclusive
+func tokensSpan(line Line, from int, to int) span {
+	return span{line: line.lineNum, column: int(line.tokens[from].column), endColumn: int(line.tokens[to].endColumn)}
+}
+
+func (s span) location(fileName string) Location {
+	return Location{FileName: fileName, Line: s.line, Column: s.column, EndColumn: s.endColumn}
+}
+
+// newDiagnostic creates error Diagnostic, its location is set when the error reaches the directive
+func newDiagnostic(code string, format string, a ...any) *Diagnostic {
+	return &Diagnostic{Severity: SeverityError, Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// at sets position of the diagnostic in the file of the directive
+func (d *Diagnostic) at(s span) *Diagnostic {
+	d.Line, d.Column, d.EndColumn = s.line, s.column, s.endColumn
+	return d
+}
+
+// withFile sets file of the diagnostic
+func (d *Diagnostic) withFile(fileName string) *Diagnostic {
+	d.FileName = fileName
+	return d
+}
+
+// withHint sets hint how to fix the problem
+func (d *Diagnostic) withHint(format string, a ...any) *Diagnostic {
+	d.Hint = fmt.Sprintf(format, a...)
+	return d
+}
+
+// withRelated adds related location
+func (d *Diagnostic) withRelated(l Location, message string) *Diagnostic {
+	d.Related = append(d.Related, RelatedLocation{Location: l, Message: message})
+	return d
+}
+
+// locateError converts err into Diagnostic and sets its file and line if they are not known
+func locateError(err error, code string, fileName string, lineNum int) *Diagnostic {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		d = &Diagnostic{Severity: SeverityError, Code: code, Message: err.Error()}
+	}
+	if d.FileName == "" {
+		d.FileName = fileName
+	}
+	if d.Line == 0 {
+		d.Line = lineNum
+	}
+	return d
+}
+
+// Diagnostics returns all diagnostics from errors returned by loading and GetState sorted by location.
+// Errors which are not diagnostics are returned with code E-UNKNOWN
+func Diagnostics(err error) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, e := range splitErrors(err) {
+		var d *Diagnostic
+		if errors.As(e, &d) {
+			diagnostics = append(diagnostics, *d)
+		} else {
+			diagnostics = append(diagnostics, Diagnostic{Severity: SeverityError, Code: CodeUnknown, Message: e.Error()})
+		}
+	}
+	SortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// splitWarnings separates diagnostics which are not errors from err, the rest of errors is joined again
+func splitWarnings(err error) ([]Diagnostic, error) {
+	warnings := []Diagnostic{}
+	errs := []error{}
+	for _, e := range splitErrors(err) {
+		var d *Diagnostic
+		if errors.As(e, &d) && d.Severity != SeverityError {
+			warnings = append(warnings, *d)
+		} else {
+			errs = append(errs, e)
+		}
+	}
+	return warnings, errors.Join(errs...)
+}
+
+// SortDiagnostics sorts diagnostics by file, line, column and code
+func SortDiagnostics(diagnostics []Diagnostic) {
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.FileName, b.FileName),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.Code, b.Code),
+		)
+	})
+}
+
+// HasErrors returns true if there is at least one diagnostic with error severity
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnosticMessage returns message of err without location if it is Diagnostic
+func diagnosticMessage(err error) string {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,70 @@
+package geancount
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	ledger := NewLedger()
+	loadErr := ledger.LoadFile("testdata/errors.bean")
+	_, stateErr := ledger.GetState()
+	diagnostics := append(Diagnostics(errors.Join(loadErr, stateErr)), ledger.Warnings()...)
+	SortDiagnostics(diagnostics)
+	assert.False(t, slices.ContainsFunc(Diagnostics(loadErr), func(d Diagnostic) bool { return d.Code == CodeUnknownOption }))
+
+	codes := []string{}
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+		assert.Equal(t, "testdata/errors.bean", d.FileName)
+	}
+	assert.Equal(t, []string{
+		CodeUnknownOption,
+		CodeAccountAlreadyOpen,
+		CodeCurrencyNotAllowed,
+		CodePadUnused,
+		CodeBalanceMismatch,
+		CodeInclude,
+	}, codes)
+
+	option := diagnostics[0]
+	assert.Equal(t, SeverityWarning, option.Severity)
+	assert.Equal(t, Location{FileName: "testdata/errors.bean", Line: 1, Column: 8, EndColumn: 24}, option.Location)
+
+	currency := diagnostics[2]
+	assert.Equal(t, Location{FileName: "testdata/errors.bean", Line: 8, Column: 21, EndColumn: 30}, currency.Location)
+	assert.Equal(t, "testdata/errors.bean:08 Currency USD can not be used in account Assets:Bank", currency.Error())
+
+	pad := diagnostics[3]
+	assert.Equal(t, 12, pad.Line)
+	assert.Equal(t, 1, len(pad.Related))
+	assert.Equal(t, 11, pad.Related[0].Line)
+
+	balance := diagnostics[4]
+	assert.Equal(t, 15, balance.Line)
+	assert.Equal(t, 33, balance.Column)
+	assert.Equal(t, 43, balance.EndColumn)
+	assert.True(t, HasErrors(diagnostics))
+	assert.False(t, HasErrors(diagnostics[:1]))
+}
+
+func TestDiagnosticsOfPlainErrors(t *testing.T) {
+	diagnostics := Diagnostics(errors.Join(errors.New("b"), &Diagnostic{Location: Location{FileName: "a"}, Code: CodeParse}))
+	assert.Equal(t, 2, len(diagnostics))
+	assert.Equal(t, CodeUnknown, diagnostics[0].Code)
+	assert.Equal(t, CodeParse, diagnostics[1].Code)
+}
+
+func TestWarningsAreNotErrors(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadReader("main.bean", strings.NewReader("option \"unknown\" \"x\"\n2000-01-01 open Assets:Bank\n"))
+	assert.Nil(t, err)
+	warnings := ledger.Warnings()
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, CodeUnknownOption, warnings[0].Code)
+	assert.Equal(t, SeverityWarning, warnings[0].Severity)
+}
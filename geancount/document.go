@@ -0,0 +1,165 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Document links a file like a scanned receipt to the account
+type Document struct {
+	directive
+	account     AccountName
+	path        string
+	discovered  bool
+	accountSpan span
+	pathSpan    span
+}
+
+// Account returns the account of the document
+func (d Document) Account() AccountName {
+	return d.account
+}
+
+// Path returns the path of the file resolved relative to the ledger file
+func (d Document) Path() string {
+	return d.path
+}
+
+// Discovered checks if the document is created for a file found in a documents directory
+func (d Document) Discovered() bool {
+	return d.discovered
+}
+
+// Apply checks that the account is open on the date of the document
+func (d Document) Apply(ls *LedgerState) error {
+	acc, ok := ls.accounts[d.account]
+	if !ok {
+		return newDiagnostic(CodeAccountNotOpen, "Document of unknown account %s", d.account).at(d.accountSpan)
+	}
+	if !acc.IsOpen(d.Date()) {
+		return newDiagnostic(CodeAccountClosed, "Account %s of document %s is not open on %s",
+			d.account, d.path, formatDate(d.Date())).at(d.accountSpan)
+	}
+	return nil
+}
+
+func newDocument(lg LineGroup, fileName string) (Document, error) {
+	line := lg.lines[0]
+	date, err := parseDate(line.tokens[0].text)
+	if err != nil {
+		return Document{}, ErrNotDirective
+	}
+	if len(line.tokens) != 4 || line.tokens[3].kind != SyntaxString {
+		return Document{}, fmt.Errorf("document should have an account and a path")
+	}
+	d := Document{
+		directive: directive{
+			date:     date,
+			lineNum:  line.lineNum,
+			fileName: fileName,
+		},
+		account:     AccountName(line.tokens[2].text),
+		path:        line.tokens[3].text,
+		accountSpan: tokenSpan(line, 2),
+		pathSpan:    tokenSpan(line, 3),
+	}
+	return d, nil
+}
+
+// documentDir is a directory set with documents option
+type documentDir struct {
+	name     string
+	fileName string
+	line     Line
+}
+
+// resolveDocuments resolves paths of documents of the file relative to it and checks that the files exist
+func (l *Ledger) resolveDocuments(fileName string, directives []Directive) []error {
+	errs := []error{}
+	for i, directive := range directives {
+		d, ok := directive.(Document)
+		if !ok {
+			continue
+		}
+		resolved, err := l.fsys.resolve(fileName, d.path)
+		if err == nil {
+			_, err = l.fsys.stat(resolved)
+		}
+		if err != nil {
+			errs = append(errs, newDiagnostic(CodeDocumentMissing, "Document %s does not exist", d.path).
+				at(d.pathSpan).withFile(fileName))
+			continue
+		}
+		d.path = resolved
+		directives[i] = d
+	}
+	return errs
+}
+
+// discoverDocuments creates documents for files in the directories named like YYYY-MM-DD.description.ext
+// and placed in subdirectories which mirror names of accounts like Assets/Bank. Files linked by
+// document directives are skipped, other files which do not belong to an opened account are reported
+func (l *Ledger) discoverDocuments(dirs []documentDir) error {
+	if len(dirs) == 0 {
+		return nil
+	}
+	opened := map[AccountName]bool{}
+	linked := map[string]bool{}
+	for _, directive := range l.directives {
+		switch d := directive.(type) {
+		case AccountOpen:
+			opened[d.account] = true
+		case Document:
+			linked[l.fsys.key(d.path)] = true
+		}
+	}
+	errs := []error{}
+	for _, dir := range dirs {
+		at := tokenSpan(dir.line, 2)
+		if info, err := l.fsys.stat(dir.name); err != nil || !info.IsDir() {
+			errs = append(errs, newDiagnostic(CodeDocumentMissing, "Documents directory %s does not exist",
+				dir.line.tokens[2].text).at(at).withFile(dir.fileName))
+			continue
+		}
+		err := l.fsys.walk(dir.name, func(name string, parts []string) error {
+			base := parts[len(parts)-1]
+			if strings.HasPrefix(base, ".") || linked[l.fsys.key(name)] {
+				return nil
+			}
+			account := AccountName(strings.Join(parts[:len(parts)-1], ":"))
+			if !opened[account] {
+				errs = append(errs, orphanDocument(name, dir, "it is not in a directory of an opened account"))
+				return nil
+			}
+			date, err := parseDate(base[:min(len(base), 10)])
+			if err != nil || len(base) < 12 || base[10] != '.' {
+				errs = append(errs, orphanDocument(name, dir, "its name does not start with a date like 2024-06-30."))
+				return nil
+			}
+			linked[l.fsys.key(name)] = true
+			l.directives = append(l.directives, Document{
+				directive:   directive{date: date, lineNum: dir.line.lineNum, fileName: dir.fileName},
+				account:     account,
+				path:        name,
+				discovered:  true,
+				accountSpan: at,
+				pathSpan:    at,
+			})
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, newDiagnostic(CodeDocumentMissing, "%s", err).at(at).withFile(dir.fileName))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// orphanDocument reports a file in a documents directory which is not linked to an account
+func orphanDocument(name string, dir documentDir, reason string) error {
+	d := newDiagnostic(CodeDocumentOrphan, "Document %s is not linked: %s", name, reason).
+		at(tokenSpan(dir.line, 2)).withFile(dir.fileName).
+		withHint("documents are named like Assets/Bank/2024-06-30.statement.pdf")
+	d.Severity = SeverityWarning
+	return d
+}
@@ -0,0 +1,87 @@
+package geancount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocuments(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadFile("testdata/documents/main.bean")
+	diagnostics := append(Diagnostics(err), ledger.Warnings()...)
+	assert.Len(t, diagnostics, 3)
+	codes := map[string]int{}
+	for _, d := range diagnostics {
+		codes[d.Code]++
+		assert.Equal(t, "testdata/documents/main.bean", d.FileName)
+	}
+	assert.Equal(t, map[string]int{CodeDocumentOrphan: 2, CodeDocumentMissing: 1}, codes)
+
+	dir, _ := filepath.Abs("testdata/documents/receipts")
+	assert.Equal(t, []string{dir}, ledger.DocumentDirs())
+	documents := DirectivesOf[Document](ledger)
+	assert.Len(t, documents, 4)
+	paths := []string{}
+	discovered := 0
+	for _, d := range documents {
+		paths = append(paths, string(d.Account())+" "+formatDate(d.Date())+" "+filepath.Base(d.Path()))
+		if d.Discovered() {
+			discovered++
+		}
+	}
+	assert.Equal(t, []string{
+		"Assets:Bank 2024-02-01 2024-02-01.statement.pdf",
+		"Assets:Bank 2024-02-02 missing.pdf",
+		"Expenses:Food 2024-02-10 2024-02-10.lunch.jpg",
+		"Assets:Bank 2024-03-01 2024-03-01.statement.pdf",
+	}, paths)
+	assert.Equal(t, 2, discovered)
+	_, err = ledger.GetState()
+	assert.Nil(t, err)
+}
+
+func TestDocumentsFS(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadFS(os.DirFS("testdata/documents"), "main.bean")
+	assert.Len(t, Diagnostics(err), 1)
+	assert.Len(t, ledger.Warnings(), 2)
+	assert.Equal(t, []string{"receipts"}, ledger.DocumentDirs())
+	documents := DirectivesOf[Document](ledger)
+	assert.Len(t, documents, 4)
+	assert.Equal(t, "receipts/Assets/Bank/2024-02-01.statement.pdf", documents[0].Path())
+	assert.Equal(t, "receipts/Expenses/Food/2024-02-10.lunch.jpg", documents[2].Path())
+}
+
+func TestDocumentBeforeAccountOpen(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "docs", "Assets", "Bank"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "docs", "Assets", "Bank", "2023-12-01.contract.pdf"), nil, 0o644))
+	src := "option \"documents\" \"docs\"\n\n2024-01-01 open Assets:Bank\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "main.bean"), []byte(src), 0o644))
+
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadFile(filepath.Join(dir, "main.bean")))
+	_, err := ledger.GetState()
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, CodeAccountNotOpen, diagnostics[0].Code)
+}
+
+func TestDocumentOfClosedAccount(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "contract.pdf"), nil, 0o644))
+	src := "2024-01-01 open Assets:Bank\n2024-02-01 close Assets:Bank\n" +
+		"2024-03-01 document Assets:Bank \"contract.pdf\"\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "main.bean"), []byte(src), 0o644))
+
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadFile(filepath.Join(dir, "main.bean")))
+	_, err := ledger.GetState()
+	diagnostics := Diagnostics(err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, CodeAccountClosed, diagnostics[0].Code)
+	assert.Equal(t, 3, diagnostics[0].Line)
+}
@@ -0,0 +1,255 @@
+package geancount
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// DuplicateOptions are rules of matching imported directives with the ledger
+type DuplicateOptions struct {
+	// IDKeys are metadata keys of postings with ids like fitid. Postings of the same account with the same
+	// id are duplicates, postings with different ids are never duplicates
+	IDKeys []string
+	// Window is a number of days dates of duplicate transactions can differ
+	Window int
+	// Similarity is a minimal similarity of payees or narrations from 0 to 1
+	Similarity float64
+}
+
+// DefaultDuplicateOptions are used by import command
+var DefaultDuplicateOptions = DuplicateOptions{IDKeys: []string{"fitid", "bank_ref"}, Window: 3, Similarity: 0.5}
+
+// Duplicate is an imported directive which is likely already in the ledger
+type Duplicate struct {
+	index    int
+	existing Directive
+	byID     bool
+}
+
+// Index returns index of the duplicate in imported directives
+func (d Duplicate) Index() int {
+	return d.index
+}
+
+// Existing returns the directive of the ledger the imported one duplicates
+func (d Duplicate) Existing() Directive {
+	return d.existing
+}
+
+// ByID returns true if the duplicate is found by id in metadata, otherwise it is found by fuzzy rules
+func (d Duplicate) ByID() bool {
+	return d.byID
+}
+
+// postingKey identifies postings of the same amount to the account
+type postingKey struct {
+	account  AccountName
+	currency Currency
+	value    string
+}
+
+// postingIDKey identifies postings with the id in metadata
+type postingIDKey struct {
+	account AccountName
+	key     string
+	id      string
+}
+
+// FindDuplicates finds imported transactions and balances which are already in the ledger.
+// Transactions are matched by ids in metadata of postings first, then by an amount posted to the same
+// account within the window of days and similar payees. An existing directive matches only one imported
+func (l *Ledger) FindDuplicates(imported []Directive, opts DuplicateOptions) []Duplicate {
+	byID := map[postingIDKey]int{}
+	byAmount := map[postingKey][]int{}
+	for i, d := range l.directives {
+		t, ok := d.(Transaction)
+		if !ok {
+			continue
+		}
+		for _, p := range filledPostings(t.postings) {
+			for _, key := range opts.IDKeys {
+				if id, ok := p.meta[key]; ok {
+					byID[postingIDKey{p.account, key, id}] = i
+				}
+			}
+			if p.amount.currency != "" {
+				key := postingKey{p.account, p.amount.currency, p.amount.value.String()}
+				byAmount[key] = append(byAmount[key], i)
+			}
+		}
+	}
+
+	used := map[int]bool{}
+	duplicates := []Duplicate{}
+	for index, d := range imported {
+		var found = -1
+		foundByID := false
+		switch d := d.(type) {
+		case Transaction:
+			found, foundByID = l.matchByID(d, opts, byID, used)
+			if found == -1 {
+				found = l.matchFuzzy(d, opts, byAmount, used)
+			}
+		case Balance:
+			found = slices.IndexFunc(l.directives, func(e Directive) bool {
+				b, ok := e.(Balance)
+				return ok && b.date.Equal(d.date) && b.account == d.account &&
+					b.amount.currency == d.amount.currency && b.amount.value.Equal(d.amount.value)
+			})
+		}
+		if found != -1 {
+			used[found] = true
+			duplicates = append(duplicates, Duplicate{index: index, existing: l.directives[found], byID: foundByID})
+		}
+	}
+	return duplicates
+}
+
+func (l *Ledger) matchByID(t Transaction, opts DuplicateOptions, byID map[postingIDKey]int, used map[int]bool) (int, bool) {
+	for _, p := range t.postings {
+		for _, key := range opts.IDKeys {
+			id, ok := p.meta[key]
+			if !ok {
+				continue
+			}
+			if i, ok := byID[postingIDKey{p.account, key, id}]; ok && !used[i] {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// matchFuzzy returns index of the closest by date transaction with the same posting and similar payee
+func (l *Ledger) matchFuzzy(t Transaction, opts DuplicateOptions, byAmount map[postingKey][]int, used map[int]bool) int {
+	found := -1
+	bestDays := opts.Window + 1
+	for _, p := range t.postings {
+		if p.amount.currency == "" {
+			continue
+		}
+		for _, i := range byAmount[postingKey{p.account, p.amount.currency, p.amount.value.String()}] {
+			existing := l.directives[i].(Transaction)
+			days := int(t.date.Sub(existing.date).Hours() / 24)
+			days = max(days, -days)
+			if used[i] || days >= bestDays || hasOtherID(p, existing, opts.IDKeys) ||
+				descriptionSimilarity(t, existing) < opts.Similarity {
+				continue
+			}
+			found, bestDays = i, days
+		}
+	}
+	return found
+}
+
+// hasOtherID checks if the existing transaction has a posting to the same account with a different id
+func hasOtherID(p Posting, existing Transaction, keys []string) bool {
+	for _, key := range keys {
+		id, ok := p.meta[key]
+		if !ok {
+			continue
+		}
+		for _, e := range existing.postings {
+			if other, ok := e.meta[key]; ok && e.account == p.account && other != id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filledPostings returns postings with the amount of the blank posting computed if other postings
+// have amounts in one currency
+func filledPostings(postings []Posting) []Posting {
+	blank := -1
+	sum := decimal.Zero
+	var currency Currency
+	for i, p := range postings {
+		switch {
+		case p.amount.currency == "":
+			if blank != -1 {
+				return postings
+			}
+			blank = i
+		case p.atCost && p.price == nil:
+			return postings
+		default:
+			value, c := p.amount.value, p.amount.currency
+			if p.price != nil {
+				value, c = value.Mul(p.price.value), p.price.currency
+			}
+			if currency != "" && c != currency {
+				return postings
+			}
+			sum, currency = sum.Add(value), c
+		}
+	}
+	if blank == -1 || currency == "" {
+		return postings
+	}
+	filled := slices.Clone(postings)
+	filled[blank].amount = NewAmount(sum.Neg(), currency)
+	return filled
+}
+
+// descriptionSimilarity returns the best similarity of payees and narrations of transactions,
+// it is 1 if any of transactions has no description
+func descriptionSimilarity(a, b Transaction) float64 {
+	best := -1.0
+	for _, x := range []string{a.payee, a.narration} {
+		for _, y := range []string{b.payee, b.narration} {
+			if x != "" && y != "" {
+				best = max(best, similarity(x, y))
+			}
+		}
+	}
+	if best == -1 {
+		return 1
+	}
+	return best
+}
+
+// similarity is Sørensen–Dice coefficient of bigrams of letters and digits of strings ignoring case
+func similarity(a, b string) float64 {
+	x, y := bigrams(a), bigrams(b)
+	if len(x) == 0 || len(y) == 0 {
+		if normalizeText(a) == normalizeText(b) {
+			return 1
+		}
+		return 0
+	}
+	common := 0
+	for bigram, n := range x {
+		common += min(n, y[bigram])
+	}
+	return 2 * float64(common) / float64(count(x)+count(y))
+}
+
+func normalizeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+func bigrams(s string) map[string]int {
+	runes := []rune(normalizeText(s))
+	result := map[string]int{}
+	for i := 0; i+1 < len(runes); i++ {
+		result[string(runes[i:i+2])]++
+	}
+	return result
+}
+
+func count(m map[string]int) int {
+	n := 0
+	for _, v := range m {
+		n += v
+	}
+	return n
+}
@@ -0,0 +1,88 @@
+package geancount
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const existingLedger = `2024-01-01 open Assets:Bank
+2024-01-01 open Expenses:Food
+2024-01-01 open Income:Job
+
+2024-01-02 * "Corner Cafe" "Coffee"
+  Expenses:Food  4.50 EUR
+  Assets:Bank
+
+2024-01-03 * "Corner Cafe" "Coffee"
+  Expenses:Food  4.50 EUR
+  Assets:Bank
+
+2024-01-05 * "ACME" "Salary"
+  Assets:Bank  1000.00 EUR
+    fitid: "T100"
+  Income:Job
+
+2024-01-06 balance Assets:Bank  991.00 EUR
+`
+
+func TestFindDuplicates(t *testing.T) {
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(existingLedger)))
+
+	date := func(day int) time.Time {
+		return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+	eur := func(s string) Amount {
+		return NewAmount(decimal.RequireFromString(s), "EUR")
+	}
+	bank := func(day int, payee string, amount string, id string) Transaction {
+		posting := NewPosting("Assets:Bank", eur(amount))
+		if id != "" {
+			posting = posting.WithMeta("fitid", id)
+		}
+		return NewTransaction(date(day), "*", payee, "", []Posting{posting, NewPosting("Expenses:Unknown", Amount{})})
+	}
+	imported := []Directive{
+		bank(4, "CORNER CAFE BERLIN", "-4.50", "T1"),
+		bank(4, "Corner Cafe", "-4.50", "T2"),
+		bank(4, "Corner Cafe", "-4.50", "T3"),
+		bank(1, "Salary ACME", "1000", "T100"),
+		bank(5, "ACME", "1000", "T101"),
+		bank(9, "Corner Cafe", "-4.50", ""),
+		bank(3, "Bakery", "-4.50", ""),
+		NewBalance(date(6), "Assets:Bank", eur("991")),
+		NewBalance(date(7), "Assets:Bank", eur("991")),
+	}
+	duplicates := ledger.FindDuplicates(imported, DefaultDuplicateOptions)
+	found := map[int]int{}
+	for _, d := range duplicates {
+		found[d.Index()] = d.Existing().LineNum()
+		assert.Equal(t, d.Index() == 3, d.ByID())
+	}
+	// Each existing transaction matches only one imported, the closest by date is preferred
+	assert.Equal(t, map[int]int{0: 9, 1: 5, 3: 13, 7: 18}, found)
+
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteImported(&out, imported[:2], duplicates[:1], DefaultFormatOptions))
+	assert.Equal(t, "; duplicate of main.bean:9\n"+
+		"; 2024-01-04 * \"CORNER CAFE BERLIN\" \"\"\n"+
+		";   Assets:Bank -4.50 EUR\n"+
+		";     fitid: \"T1\"\n"+
+		";   Expenses:Unknown\n\n"+
+		"2024-01-04 * \"Corner Cafe\" \"\"\n"+
+		"  Assets:Bank  -4.50 EUR\n"+
+		"    fitid: \"T2\"\n"+
+		"  Expenses:Unknown\n", out.String())
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("Corner Cafe", "CORNER-CAFE"))
+	assert.Equal(t, 0.0, similarity("Bakery", "ACME"))
+	assert.InDelta(t, 0.75, similarity("Corner Cafe", "Corner Cafe Berlin"), 0.01)
+	assert.Equal(t, 1.0, similarity("A", "a"))
+}
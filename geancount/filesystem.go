@@ -0,0 +1,130 @@
+package geancount
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// fileSystem opens ledger files and resolves includes
+type fileSystem interface {
+	open(name string) (io.ReadCloser, error)
+	// resolve returns name of the file included from the file with name parent
+	resolve(parent string, name string) (string, error)
+	// glob returns names of files matching the resolved pattern in lexical order
+	glob(pattern string) ([]string, error)
+	// key returns the same string for all names of the file
+	key(name string) string
+	stat(name string) (fs.FileInfo, error)
+	// walk calls fn for every file under the directory root with names of directories from root
+	// to the file and the file itself
+	walk(root string, fn func(name string, parts []string) error) error
+}
+
+// isGlob checks if the included name is a pattern
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// osFileSystem reads files from disk
+type osFileSystem struct{}
+
+func (osFileSystem) open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) resolve(parent string, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	absParent, err := filepath.Abs(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(absParent), name), nil
+}
+
+func (osFileSystem) glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	slices.Sort(matches)
+	return matches, err
+}
+
+func (osFileSystem) key(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+	return abs
+}
+
+func (osFileSystem) stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) walk(root string, fn func(name string, parts []string) error) error {
+	return filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		return fn(name, strings.Split(rel, string(filepath.Separator)))
+	})
+}
+
+// fsFileSystem reads files from fs.FS, absolute includes are relative to the root of FS
+type fsFileSystem struct {
+	fsys fs.FS
+}
+
+func (f fsFileSystem) open(name string) (io.ReadCloser, error) {
+	return f.fsys.Open(name)
+}
+
+func (f fsFileSystem) resolve(parent string, name string) (string, error) {
+	var resolved string
+	if strings.HasPrefix(name, "/") {
+		resolved = path.Clean(strings.TrimPrefix(name, "/"))
+	} else {
+		resolved = path.Join(path.Dir(parent), name)
+	}
+	if !fs.ValidPath(resolved) {
+		return "", fmt.Errorf("include %s is outside of the file system", name)
+	}
+	return resolved, nil
+}
+
+func (f fsFileSystem) glob(pattern string) ([]string, error) {
+	matches, err := fs.Glob(f.fsys, pattern)
+	slices.Sort(matches)
+	return matches, err
+}
+
+func (fsFileSystem) key(name string) string {
+	return path.Clean(name)
+}
+
+func (f fsFileSystem) stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, name)
+}
+
+func (f fsFileSystem) walk(root string, fn func(name string, parts []string) error) error {
+	return fs.WalkDir(f.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimPrefix(name, root+"/")
+		if root == "." {
+			rel = name
+		}
+		return fn(name, strings.Split(rel, "/"))
+	})
+}
@@ -0,0 +1,178 @@
+package geancount
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatOptions configures Format
+type FormatOptions struct {
+	// Indent is number of spaces used for indented lines
+	Indent int
+	// CurrencyColumn is a column where currencies start, if 0 it is computed from the widest line
+	CurrencyColumn int
+}
+
+// DefaultFormatOptions are used by fmt command
+var DefaultFormatOptions = FormatOptions{Indent: 2}
+
+// sourceLine is a line of the source to be formatted
+type sourceLine struct {
+	raw      string
+	verbatim bool // contains multi-line string, it is never changed
+	indented bool
+	nested   bool     // metadata of a posting, indented twice
+	tokens   []string // source text of tokens including quotes
+	kinds    []SyntaxKind
+	comment  string // including leading ;
+	number   int    // index of the amount token, -1 if line is not aligned
+}
+
+func newSourceLine(sl *SyntaxLine) sourceLine {
+	line := sourceLine{indented: sl.IsIndented(), number: -1}
+	sb := strings.Builder{}
+	glued := false
+	for _, t := range sl.tokens {
+		if t.kind != SyntaxNewline {
+			sb.WriteString(t.text)
+		}
+		if t.IsTrivia() {
+			glued = false
+			continue
+		}
+		// Tokens without whitespace between them like {100 EUR} stay together
+		if glued {
+			line.tokens[len(line.tokens)-1] += t.text
+			line.kinds[len(line.kinds)-1] = SyntaxWord
+		} else {
+			line.tokens = append(line.tokens, t.text)
+			line.kinds = append(line.kinds, t.kind)
+		}
+		glued = true
+		if strings.Contains(t.text, "\n") {
+			line.verbatim = true
+		}
+	}
+	line.raw = sb.String()
+	if c := sl.Comment(); c != nil {
+		line.comment = strings.TrimRight(c.text, " \t\r")
+	}
+	return line
+}
+
+// findNumber sets index of the amount token. Amount should have something before and currency after it
+func (l *sourceLine) findNumber() {
+	for i := 1; i < len(l.tokens)-1; i++ {
+		if l.kinds[i] == SyntaxNumber {
+			l.number = i
+			return
+		}
+	}
+}
+
+func (l sourceLine) prefix(indent string) string {
+	return l.indentation(indent) + strings.Join(l.tokens[:l.number], " ")
+}
+
+func (l sourceLine) indentation(indent string) string {
+	switch {
+	case l.nested:
+		return indent + indent
+	case l.indented:
+		return indent
+	}
+	return ""
+}
+
+// isMeta checks if the line is metadata like `key: value`
+func (l sourceLine) isMeta() bool {
+	if len(l.tokens) < 2 || l.kinds[0] != SyntaxWord {
+		return false
+	}
+	key := l.tokens[0]
+	return len(key) > 1 && key[0] >= 'a' && key[0] <= 'z' && strings.HasSuffix(key, ":")
+}
+
+// nestPostingsMeta marks metadata lines which follow postings
+func nestPostingsMeta(lines []sourceLine) {
+	inPosting := false
+	for i := range lines {
+		l := &lines[i]
+		switch {
+		case !l.indented:
+			inPosting = false
+		case len(l.tokens) == 0 || l.verbatim:
+		case l.isMeta():
+			l.nested = inPosting
+		default:
+			inPosting = l.kinds[0] == SyntaxAccount
+		}
+	}
+}
+
+// Format aligns amounts on a common column, normalizes indentation and spacing between tokens.
+// Comments, blank lines and order of directives are preserved
+func Format(src []byte, opts FormatOptions) []byte {
+	syntaxLines := ParseSyntaxTree(src).lines()
+	lines := make([]sourceLine, 0, len(syntaxLines))
+	for _, sl := range syntaxLines {
+		line := newSourceLine(sl)
+		if !line.verbatim {
+			line.findNumber()
+		}
+		lines = append(lines, line)
+	}
+	nestPostingsMeta(lines)
+
+	indent := strings.Repeat(" ", opts.Indent)
+	prefixWidth := 0
+	numberWidth := 0
+	for _, l := range lines {
+		if l.number == -1 {
+			continue
+		}
+		prefixWidth = max(prefixWidth, utf8.RuneCountInString(l.prefix(indent)))
+		numberWidth = max(numberWidth, utf8.RuneCountInString(l.tokens[l.number]))
+	}
+	// Prefix is followed by two spaces, the number and one space before currency
+	if opts.CurrencyColumn > 0 {
+		prefixWidth = max(prefixWidth, opts.CurrencyColumn-numberWidth-3)
+	}
+
+	sb := strings.Builder{}
+	for i, l := range lines {
+		if l.verbatim {
+			sb.WriteString(l.raw)
+		} else {
+			sb.WriteString(l.format(indent, prefixWidth, numberWidth))
+		}
+		if syntaxLines[i].endsWithNewline() {
+			sb.WriteByte('\n')
+		}
+	}
+	return []byte(sb.String())
+}
+
+// format returns the line with normalized spacing and the amount aligned
+func (l sourceLine) format(indent string, prefixWidth, numberWidth int) string {
+	var s string
+	if l.number == -1 {
+		s = strings.Join(l.tokens, " ")
+		if len(l.tokens) > 0 || l.comment != "" {
+			s = l.indentation(indent) + s
+		}
+	} else {
+		prefix := l.prefix(indent)
+		s = prefix + strings.Repeat(" ", prefixWidth-utf8.RuneCountInString(prefix)+2)
+		number := l.tokens[l.number]
+		s += strings.Repeat(" ", numberWidth-utf8.RuneCountInString(number)) + number
+		s += " " + strings.Join(l.tokens[l.number+1:], " ")
+	}
+	if l.comment != "" {
+		if len(l.tokens) > 0 {
+			s += " "
+		}
+		s += l.comment
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package geancount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	src := `; header comment
+option "operating_currency" "EUR"
+
+2000-01-01   open Assets:Bank EUR ; bank
+2000-01-02 * "Shop" "Multi
+  line  narration"
+    Assets:Bank    -1,000.5 EUR
+	Expenses:Food  1000.50 EUR {1 EUR}   ; food
+   ; indented comment
+
+2000-01-03 balance Assets:Bank  0 EUR
+`
+	expected := `; header comment
+option "operating_currency" "EUR"
+
+2000-01-01 open Assets:Bank EUR ; bank
+2000-01-02 * "Shop" "Multi
+  line  narration"
+  Assets:Bank                   -1,000.5 EUR
+  Expenses:Food                  1000.50 EUR {1 EUR} ; food
+  ; indented comment
+
+2000-01-03 balance Assets:Bank         0 EUR
+`
+	formatted := Format([]byte(src), DefaultFormatOptions)
+	assert.Equal(t, expected, string(formatted))
+	// Formatting is idempotent
+	assert.Equal(t, expected, string(Format(formatted, DefaultFormatOptions)))
+}
+
+func TestFormatCurrencyColumn(t *testing.T) {
+	src := "2000-01-02 *\n  Assets:Bank  -1 EUR\n  Expenses:Food  1 EUR"
+	expected := "2000-01-02 *\n  Assets:Bank        -1 EUR\n  Expenses:Food       1 EUR"
+	formatted := Format([]byte(src), FormatOptions{Indent: 2, CurrencyColumn: 24})
+	assert.Equal(t, expected, string(formatted))
+}
+
+func TestFormatPostingMeta(t *testing.T) {
+	src := "2000-01-02 *\n meta: \"t\"\n Assets:Bank  -1 EUR\n fitid: \"1\"\n ; comment\n     other: \"2\"\n Expenses:Food\n"
+	expected := "2000-01-02 *\n  meta: \"t\"\n  Assets:Bank  -1 EUR\n    fitid: \"1\"\n  ; comment\n    other: \"2\"\n  Expenses:Food\n"
+	assert.Equal(t, expected, string(Format([]byte(src), DefaultFormatOptions)))
+}
@@ -0,0 +1,256 @@
+package geancount
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// gnuCashBook is a part of an uncompressed GnuCash XML file used for import.
+// Elements are matched by local names without namespaces like act: and trn:
+type gnuCashBook struct {
+	Accounts     []gnuCashAccount     `xml:"book>account"`
+	Transactions []gnuCashTransaction `xml:"book>transaction"`
+	Prices       []gnuCashPrice       `xml:"book>pricedb>price"`
+	Scheduled    []struct{}           `xml:"book>schedxaction"`
+	Budgets      []struct{}           `xml:"book>budget"`
+}
+
+type gnuCashCommodity struct {
+	Space string `xml:"space"`
+	ID    string `xml:"id"`
+}
+
+type gnuCashAccount struct {
+	Name      string           `xml:"name"`
+	ID        string           `xml:"id"`
+	Type      string           `xml:"type"`
+	Commodity gnuCashCommodity `xml:"commodity"`
+	Parent    string           `xml:"parent"`
+	Slots     []gnuCashSlot    `xml:"slots>slot"`
+}
+
+type gnuCashSlot struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type gnuCashTransaction struct {
+	ID          string           `xml:"id"`
+	Currency    gnuCashCommodity `xml:"currency"`
+	DatePosted  string           `xml:"date-posted>date"`
+	Description string           `xml:"description"`
+	Splits      []gnuCashSplit   `xml:"splits>split"`
+}
+
+type gnuCashSplit struct {
+	Memo     string `xml:"memo"`
+	Value    string `xml:"value"`
+	Quantity string `xml:"quantity"`
+	Account  string `xml:"account"`
+}
+
+type gnuCashPrice struct {
+	Commodity gnuCashCommodity `xml:"commodity"`
+	Currency  gnuCashCommodity `xml:"currency"`
+	Time      string           `xml:"time>date"`
+	Value     string           `xml:"value"`
+}
+
+// gnuCashRoots are roots of accounts of GnuCash account types
+var gnuCashRoots = map[string]string{
+	"ASSET": "Assets", "BANK": "Assets", "CASH": "Assets", "STOCK": "Assets", "MUTUAL": "Assets",
+	"RECEIVABLE": "Assets", "LIABILITY": "Liabilities", "CREDIT": "Liabilities", "PAYABLE": "Liabilities",
+	"INCOME": "Income", "EXPENSE": "Expenses", "EQUITY": "Equity",
+}
+
+// gnuCashRootNames are names of top level accounts in GnuCash which are roots in the ledger
+var gnuCashRootNames = map[string][]string{
+	"Assets": {"assets", "asset"}, "Liabilities": {"liabilities", "liability"}, "Income": {"income", "incomes"},
+	"Expenses": {"expenses", "expense"}, "Equity": {"equity"},
+}
+
+// placeholder checks if the account is only a parent of other accounts
+func (a gnuCashAccount) placeholder() bool {
+	return slices.ContainsFunc(a.Slots, func(s gnuCashSlot) bool {
+		return s.Key == "placeholder" && strings.TrimSpace(s.Value) == "true"
+	})
+}
+
+// ImportGnuCash converts accounts, transactions and prices of a GnuCash XML book, compressed with gzip or not,
+// to a ledger with opening of accounts. Splits in other commodities than the currency of the transaction
+// are converted to postings with a price. Scheduled transactions, budgets and splits which can not be
+// converted are reported in the returned error
+func ImportGnuCash(r io.Reader) ([]Directive, error) {
+	reader := bufio.NewReader(r)
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+	book := gnuCashBook{}
+	if err := xml.NewDecoder(reader).Decode(&book); err != nil {
+		return nil, err
+	}
+
+	errs := []error{}
+	accounts := book.accountNames()
+	byID := map[string]gnuCashAccount{}
+	currencies := map[AccountName][]Currency{}
+	for _, a := range book.Accounts {
+		byID[a.ID] = a
+		if account, ok := accounts[a.ID]; ok && !a.placeholder() {
+			currencies[account] = []Currency{migratedCurrency(a.Commodity.ID)}
+		} else if !ok && gnuCashRoots[a.Type] == "" && a.Type != "ROOT" {
+			errs = append(errs, fmt.Errorf("account %s of type %s is not converted", a.Name, a.Type))
+		}
+	}
+
+	directives := []Directive{}
+	for _, gt := range book.Transactions {
+		t, err := gt.transaction(accounts, byID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("transaction %s %q: %w", gt.ID, gt.Description, err))
+			continue
+		}
+		if len(t.postings) > 0 {
+			directives = append(directives, t)
+		}
+	}
+	for _, p := range book.Prices {
+		price, err := p.price()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("price of %s: %w", p.Commodity.ID, err))
+			continue
+		}
+		directives = append(directives, price)
+	}
+	if n := len(book.Scheduled); n > 0 {
+		errs = append(errs, fmt.Errorf("%d scheduled transactions are not converted", n))
+	}
+	if n := len(book.Budgets); n > 0 {
+		errs = append(errs, fmt.Errorf("%d budgets are not converted", n))
+	}
+	directives = append(openAccounts(directives, currencies), directives...)
+	slices.SortStableFunc(directives, compareDirectives)
+	return directives, errors.Join(errs...)
+}
+
+// accountNames returns account names by ids of accounts. A root account of the book is skipped
+// and a top level account named like the root of its type like Expenses is not repeated.
+// Accounts of other types like TRADING are not converted
+func (b gnuCashBook) accountNames() map[string]AccountName {
+	byID := map[string]gnuCashAccount{}
+	for _, a := range b.Accounts {
+		byID[a.ID] = a
+	}
+	names := map[string]AccountName{}
+	for _, a := range b.Accounts {
+		root := gnuCashRoots[a.Type]
+		if root == "" {
+			continue
+		}
+		path := []string{}
+		for p, ok := a, true; ok && p.Type != "ROOT"; p, ok = byID[p.Parent] {
+			path = append([]string{p.Name}, path...)
+		}
+		if slices.Contains(gnuCashRootNames[root], strings.ToLower(strings.TrimSpace(path[0]))) {
+			path = path[1:]
+		}
+		names[a.ID] = migratedAccount(root, path)
+	}
+	return names
+}
+
+// transaction converts the transaction, splits with zero quantity are skipped
+func (gt gnuCashTransaction) transaction(accounts map[string]AccountName, byID map[string]gnuCashAccount) (Transaction, error) {
+	if len(gt.DatePosted) < 10 {
+		return Transaction{}, fmt.Errorf("can not parse date %q", gt.DatePosted)
+	}
+	date, err := parseDate(gt.DatePosted[:10])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("can not parse date %q", gt.DatePosted)
+	}
+	currency := migratedCurrency(gt.Currency.ID)
+	postings := []Posting{}
+	for _, split := range gt.Splits {
+		account, ok := accounts[split.Account]
+		if !ok {
+			return Transaction{}, fmt.Errorf("account %s of a split is not converted", byID[split.Account].Name)
+		}
+		quantity, err := gnuCashNumber(split.Quantity)
+		if err != nil {
+			return Transaction{}, err
+		}
+		value, err := gnuCashNumber(split.Value)
+		if err != nil {
+			return Transaction{}, err
+		}
+		if quantity.IsZero() && value.IsZero() {
+			continue
+		}
+		commodity := migratedCurrency(byID[split.Account].Commodity.ID)
+		p := NewPosting(account, NewAmount(quantity, commodity))
+		if commodity != currency {
+			if quantity.IsZero() {
+				return Transaction{}, fmt.Errorf("split of %s has value %s without quantity", account, value)
+			}
+			price := NewAmount(roundedQuotient(value, quantity).Abs(), currency)
+			p.price = &price
+		}
+		if memo := strings.TrimSpace(split.Memo); memo != "" {
+			p = p.WithMeta("memo", memo)
+		}
+		postings = append(postings, p)
+	}
+	return NewTransaction(date, "*", "", strings.TrimSpace(gt.Description), postings), nil
+}
+
+// price converts the price, its value is in the currency
+func (p gnuCashPrice) price() (Price, error) {
+	if len(p.Time) < 10 {
+		return Price{}, fmt.Errorf("can not parse date %q", p.Time)
+	}
+	date, err := parseDate(p.Time[:10])
+	if err != nil {
+		return Price{}, fmt.Errorf("can not parse date %q", p.Time)
+	}
+	value, err := gnuCashNumber(p.Value)
+	if err != nil {
+		return Price{}, err
+	}
+	return NewPrice(date, migratedCurrency(p.Commodity.ID), NewAmount(value, migratedCurrency(p.Currency.ID))), nil
+}
+
+// gnuCashNumber converts a fraction like 1250/100 to a decimal, fractions with other denominators
+// than powers of ten are rounded to 8 decimal places
+func gnuCashNumber(s string) (decimal.Decimal, error) {
+	numerator, denominator, fraction := strings.Cut(strings.TrimSpace(s), "/")
+	num, err := decimal.NewFromString(numerator)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("can not parse number %q", s)
+	}
+	if !fraction {
+		return num, nil
+	}
+	denom, err := decimal.NewFromString(denominator)
+	if err != nil || denom.IsZero() {
+		return decimal.Zero, fmt.Errorf("can not parse number %q", s)
+	}
+	for exp := int32(0); exp <= 18; exp++ {
+		if decimal.New(1, exp).Equal(denom) {
+			return num.Shift(-exp), nil
+		}
+	}
+	return roundedQuotient(num, denom), nil
+}
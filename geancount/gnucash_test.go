@@ -0,0 +1,68 @@
+package geancount
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportGnuCash(t *testing.T) {
+	src, err := os.ReadFile("testdata/book.gnucash")
+	assert.Nil(t, err)
+	directives, err := ImportGnuCash(bytes.NewReader(src))
+	assert.Equal(t, "account Trading of type TRADING is not converted\n"+
+		"transaction t4 \"Trading split\": account Trading of a split is not converted\n"+
+		"1 scheduled transactions are not converted", err.Error())
+
+	opens := directivesOf[AccountOpen](directives)
+	accounts := map[AccountName][]Currency{}
+	for _, open := range opens {
+		accounts[open.Account()] = open.Currencies()
+	}
+	assert.Equal(t, map[AccountName][]Currency{
+		"Assets:Current-account":  {"EUR"},
+		"Assets:Apple":            {"AAPL"},
+		"Expenses:Dining-out":     {"EUR"},
+		"Expenses:Other":          {"EUR"},
+		"Equity:Opening-Balances": {"EUR"},
+	}, accounts)
+
+	transactions := directivesOf[Transaction](directives)
+	assert.Equal(t, 3, len(transactions))
+	dinner := transactions[1]
+	assert.Equal(t, "Dinner", dinner.Narration())
+	assert.Equal(t, 2, len(dinner.Postings()))
+	assert.Equal(t, map[string]string{"memo": "Pizza"}, dinner.Postings()[0].Meta())
+	buy := transactions[2].Postings()[0]
+	assert.Equal(t, "3 AAPL", sourceAmount(buy.Amount()))
+	assert.Equal(t, "150 EUR", sourceAmount(*buy.Price()))
+
+	prices := directivesOf[Price](directives)
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, "155 EUR", prices[0].Amount().String())
+
+	ls := loadImported(t, directives)
+	assert.Equal(t, "504.50", ls.Balance("Assets:Current-account")["EUR"].StringFixed(2))
+	assert.Equal(t, "3", ls.Balance("Assets:Apple")["AAPL"].String())
+
+	compressed := bytes.Buffer{}
+	gz := gzip.NewWriter(&compressed)
+	_, err = gz.Write(src)
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+	unzipped, _ := ImportGnuCash(&compressed)
+	assert.Equal(t, directives, unzipped)
+}
+
+func TestGnuCashNumber(t *testing.T) {
+	for s, expected := range map[string]string{"4550/100": "45.50", "-3/1": "-3", "1/3": "0.33333333", "31000/200": "155"} {
+		value, err := gnuCashNumber(s)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, fixedString(value), s)
+	}
+	_, err := gnuCashNumber("x")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,63 @@
+package geancount
+
+import (
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// holding is a sum of lots of one currency held in an account
+type holding struct {
+	account AccountName
+	units   Amount
+	cost    Amount
+	price   *PricePoint
+}
+
+// marketValue returns value of the units at the latest known price
+func (h holding) marketValue() (Amount, bool) {
+	if h.price == nil {
+		return Amount{}, false
+	}
+	return Amount{h.units.value.Mul(h.price.amount.value), h.price.amount.currency}, true
+}
+
+// holdings returns not empty inventories sorted by account and currency
+func (ls LedgerState) holdings() []holding {
+	accounts := make([]AccountName, 0, len(ls.inventories))
+	for a := range ls.inventories {
+		accounts = append(accounts, a)
+	}
+	slices.Sort(accounts)
+	holdings := []holding{}
+	for _, a := range accounts {
+		inventory := ls.inventories[a]
+		currencies := make([]Currency, 0, len(inventory))
+		for c := range inventory {
+			currencies = append(currencies, c)
+		}
+		slices.Sort(currencies)
+		for _, c := range currencies {
+			lots := inventory[c]
+			if len(lots) == 0 {
+				continue
+			}
+			units := decimal.Zero
+			cost := decimal.Zero
+			for _, lot := range lots {
+				units = units.Add(lot.amount.value)
+				cost = cost.Add(lot.amount.value.Mul(lot.cost.value))
+			}
+			h := holding{
+				account: a,
+				units:   Amount{units, c},
+				cost:    Amount{cost, lots[0].cost.currency},
+			}
+			if price, ok := ls.latestPrice(c); ok {
+				h.price = &price
+			}
+			holdings = append(holdings, h)
+		}
+	}
+	return holdings
+}
@@ -0,0 +1,392 @@
+package geancount
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const htmlLayout = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - geancount</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.2em 0.8em; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+td.num, th.num { text-align: right; font-family: monospace; white-space: nowrap; }
+tr.total td { font-weight: bold; border-top: 2px solid #888; }
+.error { color: #a00; }
+</style>
+</head>
+<body>
+<nav>
+<a href="{{.Root}}index.html">Index</a>
+<a href="{{.Root}}balance-sheet.html">Balance sheet</a>
+<a href="{{.Root}}income-statement.html">Income statement</a>
+<a href="{{.Root}}journal.html">Journal</a>
+<a href="{{.Root}}holdings.html">Holdings</a>
+<a href="{{.Root}}errors.html">Errors ({{.ErrorCount}})</a>
+</nav>
+<h1>{{.Title}}</h1>
+{{template "content" .}}
+</body>
+</html>
+`
+
+const htmlIndex = `{{define "content"}}
+<table>
+<tr><th>Account</th><th>Opened</th><th>Closed</th></tr>
+{{range .Data}}<tr><td><a href="{{$.Root}}{{.Link}}">{{.Name}}</a></td><td>{{.Opened}}</td><td>{{.Closed}}</td></tr>
+{{end}}</table>
+{{end}}`
+
+const htmlSections = `{{define "content"}}
+{{range .Data}}<h2>{{.Title}}</h2>
+<table>
+<tr><th>Account</th><th class="num">Balance</th></tr>
+{{range .Rows}}<tr><td><a href="{{$.Root}}{{.Link}}">{{.Account}}</a></td><td class="num">{{range .Amounts}}{{.}}<br>{{end}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td class="num">{{range .Total}}{{.}}<br>{{end}}</td></tr>
+</table>
+{{end}}{{end}}`
+
+const htmlHoldings = `{{define "content"}}
+<table>
+<tr><th>Account</th><th class="num">Units</th><th class="num">Cost</th><th class="num">Price</th><th class="num">Market value</th></tr>
+{{range .Data}}<tr><td><a href="{{$.Root}}{{.Link}}">{{.Account}}</a></td><td class="num">{{.Units}}</td><td class="num">{{.Cost}}</td><td class="num">{{.Price}}</td><td class="num">{{.MarketValue}}</td></tr>
+{{end}}</table>
+{{end}}`
+
+const htmlErrors = `{{define "content"}}
+{{if .Data}}<ul>
+{{range .Data}}<li class="error">{{.}}</li>
+{{end}}</ul>{{else}}<p>No errors</p>{{end}}
+{{end}}`
+
+const htmlJournal = `{{define "content"}}
+<table>
+<tr><th>Date</th><th>Flag</th><th>Description</th><th>Other accounts</th><th class="num">Change</th><th class="num">Balance</th></tr>
+{{range .Data}}<tr><td>{{.Date}}</td><td>{{.Status}}</td><td>{{.Description}}</td><td>{{range .Others}}<a href="{{$.Root}}{{.Link}}">{{.Name}}</a><br>{{end}}</td><td class="num">{{.Change}}</td><td class="num">{{range .Balance}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+{{end}}`
+
+const htmlTransactions = `{{define "content"}}
+<table>
+<tr><th>Date</th><th>Flag</th><th>Description</th><th>Account</th><th class="num">Amount</th></tr>
+{{range .Data}}<tr><td>{{.Date}}</td><td>{{.Status}}</td><td>{{.Description}}</td><td>{{range .Postings}}<a href="{{$.Root}}{{.Link}}">{{.Account}}</a><br>{{end}}</td><td class="num">{{range .Postings}}{{index .Amounts 0}}<br>{{end}}</td></tr>
+{{end}}</table>
+{{end}}`
+
+var htmlTemplates map[string]*template.Template
+
+func init() {
+	layout := template.Must(template.New("layout").Parse(htmlLayout))
+	htmlTemplates = map[string]*template.Template{}
+	for name, content := range map[string]string{
+		"index":    htmlIndex,
+		"sections": htmlSections,
+		"holdings": htmlHoldings,
+		"errors":   htmlErrors,
+		"journal":  htmlJournal,
+		"txns":     htmlTransactions,
+	} {
+		htmlTemplates[name] = template.Must(template.Must(layout.Clone()).Parse(content))
+	}
+}
+
+type htmlPage struct {
+	Title      string
+	Root       string
+	ErrorCount int
+	Data       any
+}
+
+type htmlLink struct {
+	Name AccountName
+	Link string
+}
+
+type htmlAccountRow struct {
+	Name   AccountName
+	Link   string
+	Opened string
+	Closed string
+}
+
+type htmlBalanceRow struct {
+	Account AccountName
+	Link    string
+	Amounts []string
+}
+
+type htmlSection struct {
+	Title string
+	Rows  []htmlBalanceRow
+	Total []string
+}
+
+type htmlHoldingRow struct {
+	Account     AccountName
+	Link        string
+	Units       string
+	Cost        string
+	Price       string
+	MarketValue string
+}
+
+type htmlTransactionRow struct {
+	Date        string
+	Status      string
+	Description string
+	Postings    []htmlBalanceRow
+}
+
+type htmlJournalRow struct {
+	Date        string
+	Status      string
+	Description string
+	Others      []htmlLink
+	Change      string
+	Balance     []string
+}
+
+// htmlReport renders pages of the static report for a LedgerState
+type htmlReport struct {
+	ls       LedgerState
+	errs     []error
+	accounts []AccountName
+	// pages are accounts by paths of their journal pages
+	pages map[string]AccountName
+}
+
+func newHTMLReport(ls LedgerState, err error) *htmlReport {
+	accounts := make([]AccountName, 0, len(ls.accounts))
+	for a := range ls.accounts {
+		accounts = append(accounts, a)
+	}
+	slices.SortFunc(accounts, func(i, j AccountName) int {
+		return cmp.Compare(string(i), string(j))
+	})
+	pages := make(map[string]AccountName, len(accounts))
+	for _, a := range accounts {
+		pages[accountPagePath(a)] = a
+	}
+	return &htmlReport{ls: ls, errs: splitErrors(err), accounts: accounts, pages: pages}
+}
+
+// accountPagePath returns path of the journal page of the account relative to the report root
+func accountPagePath(a AccountName) string {
+	return "accounts/" + strings.ReplaceAll(string(a), ":", ".") + ".html"
+}
+
+// pagePaths returns paths of all pages in the report
+func (r *htmlReport) pagePaths() []string {
+	paths := []string{"index.html", "balance-sheet.html", "income-statement.html", "journal.html", "holdings.html", "errors.html"}
+	for _, a := range r.accounts {
+		paths = append(paths, accountPagePath(a))
+	}
+	return paths
+}
+
+// renderPage writes the page with path to w
+func (r *htmlReport) renderPage(path string, w io.Writer) error {
+	page := htmlPage{ErrorCount: len(r.errs)}
+	var tmpl string
+	switch path {
+	case "index.html":
+		page.Title, tmpl, page.Data = "Accounts", "index", r.accountRows()
+	case "balance-sheet.html":
+		page.Title, tmpl, page.Data = "Balance sheet", "sections", r.sections("Assets", "Liabilities", "Equity")
+	case "income-statement.html":
+		page.Title, tmpl, page.Data = "Income statement", "sections", r.sections("Income", "Expenses")
+	case "journal.html":
+		page.Title, tmpl, page.Data = "Journal", "txns", r.transactionRows()
+	case "holdings.html":
+		page.Title, tmpl, page.Data = "Holdings", "holdings", r.holdingRows()
+	case "errors.html":
+		errs := make([]string, 0, len(r.errs))
+		for _, e := range r.errs {
+			errs = append(errs, e.Error())
+		}
+		page.Title, tmpl, page.Data = "Errors", "errors", errs
+	default:
+		account, ok := r.pages[path]
+		if !ok {
+			return fmt.Errorf("unknown page %s", path)
+		}
+		page.Title, tmpl, page.Data = string(account), "journal", r.journalRows(account)
+		page.Root = "../"
+	}
+	return htmlTemplates[tmpl].Execute(w, page)
+}
+
+func (r *htmlReport) accountRows() []htmlAccountRow {
+	rows := make([]htmlAccountRow, 0, len(r.accounts))
+	for _, a := range r.accounts {
+		acc := r.ls.accounts[a]
+		row := htmlAccountRow{Name: a, Link: accountPagePath(a)}
+		if len(acc.opened) > 0 {
+			row.Opened = formatDate(acc.opened[len(acc.opened)-1])
+		}
+		if len(acc.closed) > 0 && len(acc.closed) == len(acc.opened) {
+			row.Closed = formatDate(acc.closed[len(acc.closed)-1])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// sections groups balances of accounts by their root (Assets, Income etc.)
+func (r *htmlReport) sections(roots ...string) []htmlSection {
+	sections := []htmlSection{}
+	for _, root := range roots {
+		section := htmlSection{Title: root}
+		total := CurrenciesAmounts{}
+		for _, a := range r.accounts {
+			if accountRoot(a) != root {
+				continue
+			}
+			amounts := formatCurrenciesAmounts(r.ls.balances[a])
+			if len(amounts) == 0 {
+				continue
+			}
+			for c, v := range r.ls.balances[a] {
+				total[c] = total[c].Add(v)
+			}
+			section.Rows = append(section.Rows, htmlBalanceRow{Account: a, Link: accountPagePath(a), Amounts: amounts})
+		}
+		section.Total = formatCurrenciesAmounts(total)
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+func (r *htmlReport) holdingRows() []htmlHoldingRow {
+	rows := []htmlHoldingRow{}
+	for _, h := range r.ls.holdings() {
+		row := htmlHoldingRow{
+			Account: h.account,
+			Link:    accountPagePath(h.account),
+			Units:   h.units.String(),
+			Cost:    formatAmount(h.cost),
+		}
+		if h.price != nil {
+			marketValue, _ := h.marketValue()
+			row.Price = formatAmount(h.price.amount)
+			row.MarketValue = formatAmount(marketValue)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r *htmlReport) transactionRows() []htmlTransactionRow {
+	rows := make([]htmlTransactionRow, 0, len(r.ls.transactions))
+	for _, t := range r.ls.transactions {
+		row := htmlTransactionRow{
+			Date:        formatDate(t.Date()),
+			Status:      t.status,
+			Description: strings.TrimSpace(t.payee + " " + t.narration),
+		}
+		for _, p := range t.postings {
+			row.Postings = append(row.Postings, htmlBalanceRow{
+				Account: p.account,
+				Link:    accountPagePath(p.account),
+				Amounts: []string{formatAmount(p.amount)},
+			})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r *htmlReport) journalRows(account AccountName) []htmlJournalRow {
+	rows := []htmlJournalRow{}
+	balance := CurrenciesAmounts{}
+	for _, t := range r.ls.transactions {
+		for _, p := range t.postings {
+			if p.account != account {
+				continue
+			}
+			balance[p.amount.currency] = balance[p.amount.currency].Add(p.amount.value)
+			row := htmlJournalRow{
+				Date:        formatDate(t.Date()),
+				Status:      t.status,
+				Description: strings.TrimSpace(t.payee + " " + t.narration),
+				Change:      formatAmount(p.amount),
+				Balance:     formatCurrenciesAmounts(balance),
+			}
+			for _, other := range t.postings {
+				if other.account != account && !slices.ContainsFunc(row.Others, func(l htmlLink) bool { return l.Name == other.account }) {
+					row.Others = append(row.Others, htmlLink{Name: other.account, Link: accountPagePath(other.account)})
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// accountRoot returns the first component of the account name
+func accountRoot(a AccountName) string {
+	root, _, _ := strings.Cut(string(a), ":")
+	return root
+}
+
+// formatAmount formats amount with at least two decimal places
+func formatAmount(a Amount) string {
+	if a.value.Equal(a.value.Round(2)) {
+		return fmt.Sprintf("%s %s", a.value.StringFixed(2), a.currency)
+	}
+	return a.String()
+}
+
+// formatCurrenciesAmounts formats non zero amounts sorted by currency
+func formatCurrenciesAmounts(ca CurrenciesAmounts) []string {
+	currencies := make([]Currency, 0, len(ca))
+	for c, v := range ca {
+		if !v.IsZero() {
+			currencies = append(currencies, c)
+		}
+	}
+	slices.SortFunc(currencies, func(i, j Currency) int {
+		return cmp.Compare(string(i), string(j))
+	})
+	amounts := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		amounts = append(amounts, formatAmount(Amount{ca[c], c}))
+	}
+	return amounts
+}
+
+// WriteHTML renders the static report site of the LedgerState into outDir.
+// err is the error returned by loading and GetState, it is shown on the errors page
+func (l *Ledger) WriteHTML(ls LedgerState, err error, outDir string) error {
+	report := newHTMLReport(ls, err)
+	for _, path := range report.pagePaths() {
+		filename := filepath.Join(outDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		err = report.renderPage(path, file)
+		closeErr := file.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package geancount
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTML(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadFile("testdata/prices.bean")
+	assert.Nil(t, err)
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+
+	outDir := t.TempDir()
+	err = ledger.WriteHTML(ls, err, outDir)
+	assert.Nil(t, err)
+
+	for _, name := range []string{"index.html", "balance-sheet.html", "income-statement.html", "journal.html", "holdings.html", "errors.html", "accounts/Assets.Bank.html"} {
+		_, err := os.Stat(filepath.Join(outDir, name))
+		assert.Nil(t, err, name)
+	}
+	journal, err := os.ReadFile(filepath.Join(outDir, "accounts", "Assets.Invest.html"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(journal), `href="../accounts/Assets.Bank.html"`))
+	assert.False(t, strings.Contains(string(journal), "<script"))
+
+	holdings, err := os.ReadFile(filepath.Join(outDir, "holdings.html"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(holdings), "60.00 EUR"))
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.00 EUR", formatAmount(Amount{value: decimal.RequireFromString("12.000"), currency: "EUR"}))
+	assert.Equal(t, "0.125 EUR", formatAmount(Amount{value: decimal.RequireFromString("0.125"), currency: "EUR"}))
+}
@@ -0,0 +1,97 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportConfig is a part of configs of all importers
+type ImportConfig struct {
+	// Account is the account of the statement
+	Account AccountName `yaml:"account"`
+	// ContraAccount is the other side of transactions, its posting is left blank
+	ContraAccount AccountName `yaml:"contra_account"`
+	// Flag of imported transactions, * by default
+	Flag string `yaml:"flag"`
+}
+
+var defaultImportConfig = ImportConfig{Flag: "*"}
+
+// BankConfig describes accounts of bank statements
+type BankConfig struct {
+	ImportConfig `yaml:",inline"`
+	// Accounts are accounts of statements by IBAN or number of the account, Account is used for others
+	Accounts map[string]AccountName `yaml:"accounts"`
+}
+
+// LoadBankConfig reads the config from YAML file
+func LoadBankConfig(filename string) (BankConfig, error) {
+	return loadImportConfig(filename, ParseBankConfig)
+}
+
+// ParseBankConfig parses YAML config, fills defaults and checks it
+func ParseBankConfig(data []byte) (BankConfig, error) {
+	config := BankConfig{ImportConfig: defaultImportConfig}
+	if err := parseImportConfig(data, &config, &config.ImportConfig); err != nil {
+		return config, err
+	}
+	return config, config.validateAccounts()
+}
+
+// account returns account of the statement with id
+func (c BankConfig) account(id string) AccountName {
+	if account, ok := c.Accounts[id]; ok {
+		return account
+	}
+	return c.Account
+}
+
+// validateAccounts checks accounts of statements
+func (c BankConfig) validateAccounts() error {
+	for id, account := range c.Accounts {
+		if !isAccount(string(account)) {
+			return fmt.Errorf("account %q of %s is not a valid account", account, id)
+		}
+	}
+	return nil
+}
+
+// validate checks accounts of the config
+func (c ImportConfig) validate() error {
+	if !isAccount(string(c.Account)) {
+		return fmt.Errorf("account %q is not a valid account", c.Account)
+	}
+	if !isAccount(string(c.ContraAccount)) {
+		return fmt.Errorf("contra_account %q is not a valid account", c.ContraAccount)
+	}
+	return nil
+}
+
+// parseImportConfig decodes YAML to config which already has defaults, unknown keys are errors
+func parseImportConfig(data []byte, config any, common *ImportConfig) error {
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return common.validate()
+}
+
+// loadImportConfig reads the file and parses it with parse
+func loadImportConfig[T any](filename string, parse func([]byte) (T, error)) (T, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		var config T
+		return config, err
+	}
+	config, err := parse(data)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", filename, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,599 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// JournalConfig describes how a ledger-cli or hledger journal is converted
+type JournalConfig struct {
+	// Commodities are currencies of commodities like $ or €, common symbols are mapped by default
+	Commodities map[string]Currency `yaml:"commodities"`
+	// Accounts rename accounts or their parents like Bank to Assets:Bank before names are sanitized
+	Accounts map[string]AccountName `yaml:"accounts"`
+	// DecimalComma is set if amounts are written like 1.000,50
+	DecimalComma bool `yaml:"decimal_comma"`
+}
+
+// journalCommodities are ISO codes of common currency symbols
+var journalCommodities = map[string]Currency{
+	"$": "USD", "US$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY", "₹": "INR", "₽": "RUB", "₩": "KRW",
+	"₺": "TRY", "₴": "UAH", "₪": "ILS", "zł": "PLN", "R$": "BRL", "C$": "CAD", "A$": "AUD", "Fr.": "CHF",
+	"kr": "SEK", "₿": "BTC",
+}
+
+// journalRoots are roots of accounts by lowercase names of top level accounts of journals
+var journalRoots = map[string]string{
+	"assets": "Assets", "asset": "Assets", "liabilities": "Liabilities", "liability": "Liabilities",
+	"income": "Income", "revenue": "Income", "revenues": "Income", "expenses": "Expenses", "expense": "Expenses",
+	"equity": "Equity",
+}
+
+// LoadJournalConfig reads the config from YAML file
+func LoadJournalConfig(filename string) (JournalConfig, error) {
+	return loadImportConfig(filename, ParseJournalConfig)
+}
+
+// ParseJournalConfig parses YAML config and checks it
+func ParseJournalConfig(data []byte) (JournalConfig, error) {
+	config := JournalConfig{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, err
+	}
+	for symbol, currency := range config.Commodities {
+		if currency == "" || !isCurrency(string(currency)) {
+			return config, fmt.Errorf("currency %q of %s is not a valid currency", currency, symbol)
+		}
+	}
+	for name, account := range config.Accounts {
+		if !isAccount(string(account)) {
+			return config, fmt.Errorf("account %q of %s is not a valid account", account, name)
+		}
+	}
+	return config, nil
+}
+
+// journalConverter is a state of conversion of a journal and its includes
+type journalConverter struct {
+	config           JournalConfig
+	year             int
+	decimalComma     bool
+	defaultCommodity Currency
+	aliases          [][2]string
+	parents          []string
+	directives       []Directive
+	declared         map[AccountName][]Currency
+	included         map[string]bool
+	reported         map[string]bool
+	errs             []error
+}
+
+// ConvertJournal converts transactions, prices and balance assertions of a ledger-cli or hledger journal
+// and its includes to directives with opening of accounts on their first use.
+// Periodic transactions are ignored, automated transactions and other entries which can not be
+// converted are reported in the returned error
+func ConvertJournal(filename string, config JournalConfig) ([]Directive, error) {
+	c := &journalConverter{
+		config:       config,
+		year:         time.Now().Year(),
+		decimalComma: config.DecimalComma,
+		declared:     map[AccountName][]Currency{},
+		included:     map[string]bool{},
+		reported:     map[string]bool{},
+	}
+	if err := c.convertFile(filename); err != nil {
+		return nil, err
+	}
+	directives := append(openAccounts(c.directives, c.declared), c.directives...)
+	slices.SortStableFunc(directives, compareDirectives)
+	return directives, errors.Join(c.errs...)
+}
+
+// report adds an error of the line of the file
+func (c *journalConverter) report(filename string, line int, format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf("%s:%d: %s", filename, line, fmt.Sprintf(format, args...)))
+}
+
+// reportOnce adds an error not related to a line once
+func (c *journalConverter) reportOnce(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if !c.reported[message] {
+		c.reported[message] = true
+		c.errs = append(c.errs, errors.New(message))
+	}
+}
+
+func (c *journalConverter) convertFile(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	c.included[abs] = true
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.ReplaceAll(strings.TrimPrefix(string(src), "\ufeff"), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		lineNum := i + 1
+		// block collects following indented lines of an entry
+		block := func() []string {
+			start := i + 1
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && isIndentedLine(lines[i+1]) {
+				i++
+			}
+			return lines[start : i+1]
+		}
+		if line == "" || isIndentedLine(line) || strings.ContainsRune(";#%|*", rune(line[0])) {
+			continue
+		}
+		keyword, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch {
+		case isDigit(line[0]):
+			c.convertTransaction(filename, lineNum, line, block())
+		case line[0] == '=':
+			block()
+			c.report(filename, lineNum, "automated transaction is not converted")
+		case line[0] == '~':
+			block() // Periodic transactions are budgets
+		case keyword == "P":
+			c.convertPrice(filename, lineNum, arg)
+		case keyword == "comment" || keyword == "test":
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "end "+keyword {
+				i++
+			}
+			i++
+		case keyword == "include":
+			c.include(filename, lineNum, arg)
+		case keyword == "account":
+			if account, ok := c.account(filename, lineNum, stripJournalComment(arg)); ok {
+				if _, ok := c.declared[account]; !ok {
+					c.declared[account] = nil
+				}
+			}
+			block()
+		case keyword == "alias":
+			from, to, ok := strings.Cut(arg, "=")
+			if !ok {
+				c.report(filename, lineNum, "can not parse alias %q", arg)
+				continue
+			}
+			c.aliases = append(c.aliases, [2]string{strings.TrimSpace(from), strings.TrimSpace(to)})
+		case line == "end aliases":
+			c.aliases = nil
+		case keyword == "apply" && strings.HasPrefix(arg, "account "):
+			c.parents = append(c.parents, strings.TrimSpace(strings.TrimPrefix(arg, "account ")))
+		case line == "end apply account" || line == "end apply":
+			if len(c.parents) > 0 {
+				c.parents = c.parents[:len(c.parents)-1]
+			}
+		case keyword == "year" || keyword == "Y":
+			year, err := strconv.Atoi(arg)
+			if err != nil {
+				c.report(filename, lineNum, "can not parse year %q", arg)
+				continue
+			}
+			c.year = year
+		case keyword == "D":
+			if amount, ok := c.amount(filename, lineNum, arg); ok {
+				c.defaultCommodity = amount.currency
+			}
+		case keyword == "decimal-mark":
+			c.decimalComma = arg == ","
+		case slices.Contains([]string{"commodity", "payee", "tag", "define", "check", "assert", "value", "N", "C", "end"}, keyword):
+			block() // Declarations are not needed in the ledger
+		default:
+			block()
+			c.report(filename, lineNum, "%s is not converted", keyword)
+		}
+	}
+	return nil
+}
+
+// isIndentedLine checks if the line belongs to the entry above
+func isIndentedLine(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// include converts included files, paths are relative to the including file and can be patterns
+func (c *journalConverter) include(filename string, lineNum int, pattern string) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(filename), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		c.report(filename, lineNum, "no files match %s", pattern)
+		return
+	}
+	// Files included again are skipped so the conversion of other files goes on
+	for _, match := range matches {
+		if abs, err := filepath.Abs(match); err == nil && c.included[abs] {
+			c.report(filename, lineNum, "%s is already included", match)
+			continue
+		}
+		if err := c.convertFile(match); err != nil {
+			c.report(filename, lineNum, "%s", err)
+		}
+	}
+}
+
+// stripJournalComment removes a comment after ;
+func stripJournalComment(s string) string {
+	if i := strings.Index(s, ";"); i != -1 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// journalTags returns tags of a comment like ; :trip:food:
+func journalTags(comment string) []string {
+	tags := []string{}
+	for _, word := range strings.Fields(comment) {
+		if len(word) > 2 && strings.HasPrefix(word, ":") && strings.HasSuffix(word, ":") {
+			for _, tag := range strings.Split(word[1:len(word)-1], ":") {
+				if tag != "" && !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	return tags
+}
+
+// journalHeaderRe matches a header like 2024/01/02=2024/01/03 * (123) Payee | Note ; comment
+var journalHeaderRe = regexp.MustCompile(`^([0-9./-]+)(?:=[0-9./-]+)?\s*([*!])?\s*(?:\(([^)]*)\))?\s*(.*)$`)
+
+func (c *journalConverter) convertTransaction(filename string, lineNum int, header string, lines []string) {
+	m := journalHeaderRe.FindStringSubmatch(header)
+	if m == nil {
+		c.report(filename, lineNum, "can not parse transaction")
+		return
+	}
+	date, err := c.date(m[1])
+	if err != nil {
+		c.report(filename, lineNum, "%s", err)
+		return
+	}
+	flag := "*"
+	if m[2] == "!" {
+		flag = "!"
+	}
+	description, comment, _ := strings.Cut(m[4], ";")
+	payee, narration, ok := strings.Cut(strings.TrimSpace(description), "|")
+	if !ok {
+		payee, narration = "", payee
+	}
+	tags := journalTags(comment)
+	postings := []Posting{}
+	balances := []Directive{}
+	for j, line := range lines {
+		postingLine := lineNum + j + 1
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ";") {
+			if len(postings) == 0 {
+				tags = append(tags, journalTags(line[1:])...)
+			}
+			continue
+		}
+		p, balance, ok := c.posting(filename, postingLine, line, date)
+		if !ok {
+			return
+		}
+		if p.account != "" {
+			postings = append(postings, p)
+		}
+		if balance != nil {
+			balances = append(balances, *balance)
+		}
+	}
+	blanks := 0
+	for _, p := range postings {
+		if p.amount.currency == "" {
+			blanks++
+		}
+	}
+	if blanks > 1 {
+		c.report(filename, lineNum, "transaction has %d postings without amounts", blanks)
+		return
+	}
+	t := NewTransaction(date, flag, strings.TrimSpace(payee), strings.TrimSpace(narration), postings).WithTags(tags...)
+	c.directives = append(c.directives, t)
+	c.directives = append(c.directives, balances...)
+}
+
+// posting converts a posting line, virtual postings in parentheses are skipped.
+// A balance assertion becomes a balance on the next day, the posting of a balance assignment is blank
+func (c *journalConverter) posting(filename string, lineNum int, line string, date time.Time) (Posting, *Balance, bool) {
+	if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "! ") {
+		line = strings.TrimSpace(line[2:])
+	}
+	line = stripJournalComment(line)
+	// An account is separated from an amount by a tab or two spaces, names can have single spaces
+	name, rest := line, ""
+	i := strings.Index(line, "  ")
+	if tab := strings.Index(line, "\t"); tab != -1 && (i == -1 || tab < i) {
+		i = tab
+	}
+	if i != -1 {
+		name, rest = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i:])
+	}
+	if strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")") {
+		c.report(filename, lineNum, "virtual posting to %s is skipped", name[1:len(name)-1])
+		return Posting{}, nil, true
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+	account, ok := c.account(filename, lineNum, name)
+	if !ok {
+		return Posting{}, nil, false
+	}
+
+	var assertion string
+	if i := indexOutsideBraces(rest, '='); i != -1 {
+		rest, assertion = strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i+1:])
+	}
+	if strings.HasPrefix(rest, "(") {
+		c.report(filename, lineNum, "amount expression %s is not converted", rest)
+		return Posting{}, nil, false
+	}
+	var price string
+	total := false
+	if i := indexOutsideBraces(rest, '@'); i != -1 {
+		rest, price = strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i+1:])
+		if strings.HasPrefix(price, "@") {
+			price, total = strings.TrimSpace(price[1:]), true
+		}
+	}
+	var cost string
+	totalCost := false
+	if i := strings.Index(rest, "{"); i != -1 {
+		cost = rest[i:]
+		rest = strings.TrimSpace(rest[:i])
+		end := strings.Index(cost, "}")
+		if end == -1 {
+			c.report(filename, lineNum, "can not parse cost %s", cost)
+			return Posting{}, nil, false
+		}
+		if strings.HasPrefix(cost, "{{") {
+			cost, totalCost = strings.Trim(cost[:end], "{ "), true
+		} else {
+			cost = strings.TrimSpace(cost[1:end])
+		}
+		cost = strings.TrimPrefix(cost, "=") // Fixed price of lots
+	}
+
+	p := NewPosting(account, Amount{})
+	if rest != "" {
+		amount, ok := c.amount(filename, lineNum, rest)
+		if !ok {
+			return Posting{}, nil, false
+		}
+		p.amount = amount
+	}
+	perUnit := func(s string, isTotal bool) (*Amount, bool) {
+		amount, ok := c.amount(filename, lineNum, s)
+		if !ok {
+			return nil, false
+		}
+		if isTotal {
+			if p.amount.value.IsZero() {
+				c.report(filename, lineNum, "total price of zero amount")
+				return nil, false
+			}
+			amount.value = roundedQuotient(amount.value, p.amount.value.Abs())
+		}
+		return &amount, true
+	}
+	switch {
+	case cost != "" && p.amount.currency != "":
+		unit, ok := perUnit(cost, totalCost)
+		if !ok {
+			return Posting{}, nil, false
+		}
+		p = NewPostingAtCost(account, p.amount, unit)
+	case price != "" && p.amount.currency != "":
+		unit, ok := perUnit(price, total)
+		if !ok {
+			return Posting{}, nil, false
+		}
+		p.price = unit
+	}
+
+	if assertion == "" {
+		return p, nil, true
+	}
+	amount, ok := c.amount(filename, lineNum, assertion)
+	if !ok {
+		return Posting{}, nil, false
+	}
+	balance := NewBalance(date.AddDate(0, 0, 1), account, amount)
+	return p, &balance, true
+}
+
+func (c *journalConverter) convertPrice(filename string, lineNum int, arg string) {
+	fields := strings.Fields(stripJournalComment(arg))
+	if len(fields) < 3 {
+		c.report(filename, lineNum, "can not parse price")
+		return
+	}
+	date, err := c.date(fields[0])
+	if err != nil {
+		c.report(filename, lineNum, "%s", err)
+		return
+	}
+	fields = fields[1:]
+	if strings.Contains(fields[0], ":") {
+		fields = fields[1:] // Time
+	}
+	rest := strings.Join(fields, " ")
+	commodity, rest, _ := strings.Cut(rest, " ")
+	if strings.HasPrefix(commodity, `"`) {
+		// Quoted commodities can have spaces
+		quoted, after, ok := strings.Cut(strings.Join(fields, " ")[1:], `"`)
+		if !ok {
+			c.report(filename, lineNum, "can not parse price")
+			return
+		}
+		commodity, rest = quoted, after
+	}
+	currency, ok := c.currency(filename, lineNum, commodity)
+	if !ok {
+		return
+	}
+	amount, ok := c.amount(filename, lineNum, rest)
+	if !ok {
+		return
+	}
+	c.directives = append(c.directives, NewPrice(date, currency, amount))
+}
+
+// indexOutsideBraces returns the index of the first c which is not inside a cost in braces like {=$150}
+func indexOutsideBraces(s string, c byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			depth++
+		case s[i] == '}' && depth > 0:
+			depth--
+		case s[i] == c && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+// date parses dates like 2024/01/02, 2024-01-02, 2024.01.02 or 01/02 in the year of the year directive
+func (c *journalConverter) date(s string) (time.Time, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	numbers := []int{}
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("can not parse date %q", s)
+		}
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 2 {
+		numbers = append([]int{c.year}, numbers...)
+	}
+	if len(numbers) != 3 {
+		return time.Time{}, fmt.Errorf("can not parse date %q", s)
+	}
+	date := time.Date(numbers[0], time.Month(numbers[1]), numbers[2], 0, 0, 0, 0, time.UTC)
+	if int(date.Month()) != numbers[1] || date.Day() != numbers[2] {
+		return time.Time{}, fmt.Errorf("can not parse date %q", s)
+	}
+	return date, nil
+}
+
+// account converts a name of the journal to an account, parents of apply account, aliases and
+// accounts of the config are applied first. Accounts with unknown top level names are put under Assets
+func (c *journalConverter) account(filename string, lineNum int, name string) (AccountName, bool) {
+	if name == "" {
+		c.report(filename, lineNum, "posting without account")
+		return "", false
+	}
+	for i := len(c.parents) - 1; i >= 0; i-- {
+		name = c.parents[i] + ":" + name
+	}
+	rename := func(name string, from string, to string) string {
+		if name == from || strings.HasPrefix(name, from+":") {
+			return to + name[len(from):]
+		}
+		return name
+	}
+	for _, alias := range c.aliases {
+		name = rename(name, alias[0], alias[1])
+	}
+	longest := ""
+	for from := range c.config.Accounts {
+		if (name == from || strings.HasPrefix(name, from+":")) && len(from) > len(longest) {
+			longest = from
+		}
+	}
+	if longest != "" {
+		name = rename(name, longest, string(c.config.Accounts[longest]))
+	}
+	components := strings.Split(name, ":")
+	root, ok := journalRoots[strings.ToLower(strings.TrimSpace(components[0]))]
+	if ok {
+		components = components[1:]
+	} else {
+		root = "Assets"
+		c.reportOnce("accounts of %s are converted to Assets:%s", components[0], accountComponent(components[0]))
+	}
+	return migratedAccount(root, components), true
+}
+
+// currency maps the commodity to a currency, commodities without letters and digits have to be in the table
+func (c *journalConverter) currency(filename string, lineNum int, commodity string) (Currency, bool) {
+	commodity = strings.Trim(commodity, `"`)
+	if currency, ok := c.config.Commodities[commodity]; ok {
+		return currency, true
+	}
+	if currency, ok := journalCommodities[commodity]; ok {
+		return currency, true
+	}
+	if !strings.ContainsFunc(commodity, func(r rune) bool { return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) }) {
+		c.report(filename, lineNum, "commodity %q is not mapped to a currency", commodity)
+		return "", false
+	}
+	return migratedCurrency(commodity), true
+}
+
+// journalCommodity matches a quoted commodity or a commodity without digits and special characters
+const journalCommodity = `"[^"]+"|[^\s0-9\-+.,;@{}()=*/"]+`
+
+// journalAmountRe matches amounts like $-1,000.50, -$10, 10 EUR or 2 "AAPL 2"
+var journalAmountRe = regexp.MustCompile(`^(-)?\s*(` + journalCommodity + `)?\s*(-)?\s*([0-9][0-9.,]*|\.[0-9]+)\s*(` + journalCommodity + `)?$`)
+
+// amount parses the amount, an amount without a commodity gets the default commodity
+func (c *journalConverter) amount(filename string, lineNum int, s string) (Amount, bool) {
+	m := journalAmountRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[2] != "" && m[5] != "" {
+		c.report(filename, lineNum, "can not parse amount %q", s)
+		return Amount{}, false
+	}
+	number := m[4]
+	if c.decimalComma {
+		number = strings.ReplaceAll(strings.ReplaceAll(number, ".", ""), ",", ".")
+	} else {
+		number = strings.ReplaceAll(number, ",", "")
+	}
+	value, err := decimal.NewFromString(number)
+	if err != nil {
+		c.report(filename, lineNum, "can not parse amount %q", s)
+		return Amount{}, false
+	}
+	if m[1] != "" || m[3] != "" {
+		value = value.Neg()
+	}
+	currency := c.defaultCommodity
+	if commodity := m[2] + m[5]; commodity != "" {
+		var ok bool
+		if currency, ok = c.currency(filename, lineNum, commodity); !ok {
+			return Amount{}, false
+		}
+	}
+	if currency == "" {
+		c.report(filename, lineNum, "amount %q has no commodity", s)
+		return Amount{}, false
+	}
+	return NewAmount(value, currency), true
+}
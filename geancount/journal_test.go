@@ -0,0 +1,108 @@
+package geancount
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJournalConfig(t *testing.T) {
+	config, err := ParseJournalConfig([]byte("commodities: {\"£\": GBP}\naccounts: {Personal: Assets:Personal}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, Currency("GBP"), config.Commodities["£"])
+
+	for _, src := range []string{
+		"commodities: {\"$\": dollar}\n",
+		"accounts: {Bank: Bank}\n",
+		"unknown: 1\n",
+	} {
+		_, err := ParseJournalConfig([]byte(src))
+		assert.NotNil(t, err, src)
+	}
+}
+
+func TestConvertJournal(t *testing.T) {
+	config, err := ParseJournalConfig([]byte("accounts: {Personal:Expenses: Expenses:Personal}\n"))
+	assert.Nil(t, err)
+	directives, err := ConvertJournal("testdata/journal/main.journal", config)
+	assert.Equal(t, "testdata/journal/main.journal:21: automated transaction is not converted\n"+
+		"testdata/journal/main.journal:36: virtual posting to Budget:Food is skipped\n"+
+		"testdata/journal/main.journal:56: amount expression ($1 * 2) is not converted\n"+
+		"accounts of Personal are converted to Assets:Personal", err.Error())
+
+	accounts := map[AccountName]time.Time{}
+	for _, open := range directivesOf[AccountOpen](directives) {
+		accounts[open.Account()] = open.Date()
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	assert.Equal(t, map[AccountName]time.Time{
+		"Assets:Checking":         date(1, 1),
+		"Liabilities:Visa-Card":   date(1, 1),
+		"Equity:Opening-Balances": date(1, 1),
+		"Expenses:Food-Drink":     date(1, 3),
+		"Income:Job":              date(1, 5),
+		"Assets:Broker":           date(1, 10),
+		"Income:Gains":            date(1, 12),
+		"Expenses:Personal:Gifts": date(1, 20),
+		"Assets:Personal:Wallet":  date(1, 20),
+		"Expenses:Rent":           date(2, 1),
+	}, accounts)
+
+	transactions := directivesOf[Transaction](directives)
+	assert.Equal(t, 8, len(transactions))
+	coffee := transactions[1]
+	assert.Equal(t, "!", coffee.Status())
+	assert.Equal(t, "Corner Cafe", coffee.Payee())
+	assert.Equal(t, "Coffee", coffee.Narration())
+	assert.Equal(t, []string{"food", "coffee", "morning"}, coffee.Tags())
+	assert.Equal(t, 2, len(coffee.Postings()))
+	assert.Equal(t, "Salary", transactions[2].Narration())
+	assert.Equal(t, "", transactions[2].Payee())
+
+	buy := transactions[3].Postings()[0]
+	assert.Equal(t, "150 USD", sourceAmount(*buy.Price()))
+	sell := transactions[4].Postings()[0]
+	assert.True(t, sell.AtCost())
+	assert.Equal(t, "150 USD", sourceAmount(*sell.Price()))
+	fixed := transactions[5].Postings()[0]
+	assert.True(t, fixed.AtCost())
+	assert.Equal(t, "150 USD", sourceAmount(*fixed.Price()))
+	gift := transactions[6].Postings()[0]
+	assert.Equal(t, "20.00 EUR", sourceAmount(gift.Amount()))
+
+	balances := directivesOf[Balance](directives)
+	assert.Equal(t, 2, len(balances))
+	// The balance assignment of the blank posting is asserted
+	assert.Equal(t, date(1, 4), balances[0].Date())
+	assert.Equal(t, "995.50 USD", sourceAmount(balances[0].Amount()))
+	assert.Equal(t, date(1, 6), balances[1].Date())
+	assert.Equal(t, "1995.50 USD", sourceAmount(balances[1].Amount()))
+
+	prices := directivesOf[Price](directives)
+	assert.Equal(t, 2, len(prices))
+	assert.Equal(t, Currency("VANGUARD_500"), prices[1].Currency())
+	assert.Equal(t, "400.10 EUR", sourceAmount(prices[1].Amount()))
+
+	ls := loadImported(t, directives)
+	assert.Equal(t, "295.50", ls.Balance("Assets:Checking")["USD"].StringFixed(2))
+	assert.Equal(t, "7", ls.Balance("Assets:Broker")["AAPL"].String())
+}
+
+func TestConvertJournalIncludedTwice(t *testing.T) {
+	dir := t.TempDir()
+	main := "include food.journal\ninclude food.journal\ninclude main.journal\n\n" +
+		"2024/01/02 Shop\n  expenses:food  5.00 EUR\n  assets:cash\n"
+	food := "2024/01/01 Bakery\n  expenses:food  2.00 EUR\n  assets:cash\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "main.journal"), []byte(main), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "food.journal"), []byte(food), 0o644))
+
+	directives, err := ConvertJournal(filepath.Join(dir, "main.journal"), JournalConfig{})
+	assert.ErrorContains(t, err, "main.journal:2: "+filepath.Join(dir, "food.journal")+" is already included")
+	assert.ErrorContains(t, err, "main.journal:3: "+filepath.Join(dir, "main.journal")+" is already included")
+	assert.Len(t, directivesOf[Transaction](directives), 2)
+}
@@ -2,12 +2,16 @@ package geancount
 
 import (
 	"cmp"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"io/fs"
+	"maps"
 	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -24,6 +28,11 @@ type LedgerState struct {
 	balances    AccountsBalances
 	inventories map[AccountName]map[Currency][]Lot
 	prices      map[Currency][]PricePoint
+
+	// transactions are all applied transactions including generated by pads
+	transactions []Transaction
+	// lastPostings are locations of the last posting to each account
+	lastPostings map[AccountName]Location
 }
 
 const printPrecision = 5
@@ -32,6 +41,19 @@ const printPrecision = 5
 type Ledger struct {
 	directives          []Directive
 	operatingCurrencies []Currency
+	documentDirs        []documentDir
+	// warnings are diagnostics of loaded files which do not make the ledger invalid
+	warnings []Diagnostic
+	files    []string
+	includes []Include
+	// patterns are resolved glob patterns of includes
+	patterns []string
+	fsys     fileSystem
+	cache    *Cache
+	// parsed are parsed files by name, they are known only when the cache is used
+	parsed map[string]*parsedFile
+	// workers is a number of files loaded at once, GOMAXPROCS if 0
+	workers int
 }
 
 // NewLedger creates ledger
@@ -40,56 +62,223 @@ func NewLedger() *Ledger {
 	return &l
 }
 
-// LoadFile reads the file, parses it and adds content Ledger
-func (l *Ledger) LoadFile(filename string) error {
-	return l.loadFile(filename, true)
+// Warnings returns diagnostics found when files were loaded which do not make the ledger invalid.
+// They are not included in errors returned by loading
+func (l *Ledger) Warnings() []Diagnostic {
+	return slices.Clone(l.warnings)
 }
 
-func (l *Ledger) loadFile(filename string, sortDirectives bool) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
+// Directives returns all directives of the ledger sorted by date
+func (l *Ledger) Directives() []Directive {
+	return slices.Clone(l.directives)
+}
+
+// DirectivesOf returns directives of the type T, like DirectivesOf[Transaction](l)
+func DirectivesOf[T Directive](l *Ledger) []T {
+	directives := []T{}
+	for _, d := range l.directives {
+		if typed, ok := d.(T); ok {
+			directives = append(directives, typed)
+		}
 	}
-	defer file.Close()
-	abs, err := filepath.Abs(filename)
-	if err != nil {
-		return err
+	return directives
+}
+
+// OperatingCurrencies returns currencies set with operating_currency option
+func (l *Ledger) OperatingCurrencies() []Currency {
+	return slices.Clone(l.operatingCurrencies)
+}
+
+// DocumentDirs returns directories set with documents option resolved relative to files with the option
+func (l *Ledger) DocumentDirs() []string {
+	dirs := make([]string, len(l.documentDirs))
+	for i, dir := range l.documentDirs {
+		dirs[i] = dir.name
 	}
-	parentDir := filepath.Dir(abs)
+	return dirs
+}
 
-	lines, err := parseInput(file)
-	if err != nil {
-		return err
+// Files returns names of the loaded file and all included files
+func (l *Ledger) Files() []string {
+	return slices.Clone(l.files)
+}
+
+// Includes returns the include graph, edges are in order the files are loaded.
+// Includes which form a cycle or include a file again are in the graph too
+func (l *Ledger) Includes() []Include {
+	return slices.Clone(l.includes)
+}
+
+// LoadFile reads the file, parses it and adds content Ledger.
+// Included files are read and parsed concurrently
+func (l *Ledger) LoadFile(filename string) error {
+	l.fsys = osFileSystem{}
+	err := l.load(filename, nil)
+	l.cache.prune(l.files)
+	return locateOpenError(err, filename)
+}
+
+// LoadReader parses content of r and adds it to the Ledger.
+// name is used in errors and includes are resolved relative to its directory
+func (l *Ledger) LoadReader(name string, r io.Reader) error {
+	l.fsys = osFileSystem{}
+	err := l.load(name, r)
+	l.cache.prune(l.files)
+	return err
+}
+
+// LoadFS reads the root file from fsys and adds its content to the Ledger.
+// Includes are resolved inside fsys
+func (l *Ledger) LoadFS(fsys fs.FS, root string) error {
+	l.fsys = fsFileSystem{fsys: fsys}
+	err := l.load(root, nil)
+	l.cache.prune(l.files)
+	return locateOpenError(err, root)
+}
+
+// locateOpenError converts error of opening the root file to Diagnostic
+func locateOpenError(err error, filename string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return locateError(err, CodeIO, filename, 0)
+	}
+	return err
+}
+
+// parseFile parses source of the file or takes it from the cache if the content is not changed.
+// It is called concurrently for different files
+func (l *Ledger) parseFile(filename string, src []byte) (*parsedFile, error) {
+	hash := sha256.Sum256(src)
+	if parsed, ok := l.cache.lookup(filename, hash); ok {
+		return parsed, nil
 	}
-	lineGroups, err := groupLines(lines)
+	lineGroups, err := groupLines(syntaxTreeLines(ParseSyntaxTree(src)))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	parsed := createDirectives(lineGroups, filename)
+	l.cache.store(filename, hash, parsed)
+	return parsed, nil
+}
 
-	err = l.createDirectives(lineGroups, filename, parentDir)
-	if sortDirectives {
-		l.sortDirectives()
+func newLedgerState() LedgerState {
+	return LedgerState{
+		accounts:     map[AccountName]Account{},
+		balances:     AccountsBalances{},
+		inventories:  map[AccountName]map[Currency][]Lot{},
+		prices:       map[Currency][]PricePoint{},
+		transactions: []Transaction{},
+		lastPostings: map[AccountName]Location{},
 	}
-	return err
 }
 
-// GetState compute state of ledger
+// GetState compute state of ledger. If the cache is used the state is computed from the latest
+// checkpoint where directives are not changed since the previous call
 func (l *Ledger) GetState() (LedgerState, error) {
-	ls := LedgerState{}
-	ls.accounts = map[AccountName]Account{}
-	ls.balances = AccountsBalances{}
-	ls.inventories = map[AccountName]map[Currency][]Lot{}
-	ls.prices = map[Currency][]PricePoint{}
+	ls := newLedgerState()
 	errs := []error{}
-	for _, directive := range l.directives {
+	start := 0
+	if l.cache != nil {
+		start, ls, errs = l.cache.restore(l.directiveKeys())
+	}
+	for i, directive := range l.directives[start:] {
+		if l.cache != nil {
+			l.cache.checkpoint(start+i, ls, errs)
+		}
 		err := directive.Apply(&ls)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("%s:%02d %s", directive.FileName(), directive.LineNum(), err.Error()))
+			errs = append(errs, locateError(err, CodeUnknown, directive.FileName(), directive.LineNum()))
 		}
 	}
 	return ls, errors.Join(errs...)
 }
 
+// stateAt computes state at the end of the date, errors are ignored.
+// Directives are applied from the last checkpoint of the cache before the date
+func (l *Ledger) stateAt(date time.Time) LedgerState {
+	end := sort.Search(len(l.directives), func(i int) bool {
+		return l.directives[i].Date().After(date)
+	})
+	start, ls := 0, newLedgerState()
+	if l.cache != nil {
+		start, ls = l.cache.stateBefore(end, l.directiveKeys())
+	}
+	for _, directive := range l.directives[start:end] {
+		directive.Apply(&ls)
+	}
+	return ls
+}
+
+// Accounts returns all accounts sorted by name
+func (ls LedgerState) Accounts() []Account {
+	accounts := make([]Account, 0, len(ls.accounts))
+	for _, acc := range ls.accounts {
+		accounts = append(accounts, acc)
+	}
+	slices.SortFunc(accounts, func(i, j Account) int {
+		return cmp.Compare(i.name, j.name)
+	})
+	return accounts
+}
+
+// Account returns the account by name
+func (ls LedgerState) Account(name AccountName) (Account, bool) {
+	acc, ok := ls.accounts[name]
+	return acc, ok
+}
+
+// Balance returns balance of the account in all currencies
+func (ls LedgerState) Balance(name AccountName) CurrenciesAmounts {
+	return maps.Clone(ls.balances[name])
+}
+
+// Balances returns balances of all accounts
+func (ls LedgerState) Balances() AccountsBalances {
+	balances := AccountsBalances{}
+	for name, amounts := range ls.balances {
+		balances[name] = maps.Clone(amounts)
+	}
+	return balances
+}
+
+// Inventory returns lots held in the account by currency
+func (ls LedgerState) Inventory(name AccountName) map[Currency][]Lot {
+	inventory := map[Currency][]Lot{}
+	for c, lots := range ls.inventories[name] {
+		if len(lots) > 0 {
+			inventory[c] = slices.Clone(lots)
+		}
+	}
+	return inventory
+}
+
+// Prices returns known prices of the currency in order they were applied
+func (ls LedgerState) Prices(currency Currency) []PricePoint {
+	return slices.Clone(ls.prices[currency])
+}
+
+// Price returns the latest price of the currency known on the date
+func (ls LedgerState) Price(currency Currency, date time.Time) (Amount, bool) {
+	var found *PricePoint
+	for i, p := range ls.prices[currency] {
+		if p.date.After(date) {
+			continue
+		}
+		if found == nil || !p.date.Before(found.date) {
+			found = &ls.prices[currency][i]
+		}
+	}
+	if found == nil {
+		return Amount{}, false
+	}
+	return found.amount, true
+}
+
+// Transactions returns all applied transactions including ones inserted by pads
+func (ls LedgerState) Transactions() []Transaction {
+	return slices.Clone(ls.transactions)
+}
+
 // PrintBalances prints to stdput formatted balances for all accounts
 func (l *Ledger) PrintBalances(ls LedgerState, filterExpression string, printEmpty bool) error {
 	accounts := make([]AccountName, 0, len(ls.accounts))
@@ -1,7 +1,11 @@
 package geancount
 
 import (
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -31,3 +35,78 @@ func TestGetLedgerState(t *testing.T) {
 	x, _ = decimal.NewFromString("-100")
 	assert.True(t, ls.balances[AccountName("Income:Job")][curr].Equal(x))
 }
+
+func TestLedgerStateAPI(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadFile("testdata/prices.bean")
+	assert.Nil(t, err)
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(DirectivesOf[AccountOpen](ledger)))
+	assert.Equal(t, 5, len(DirectivesOf[Balance](ledger)))
+	transactions := DirectivesOf[Transaction](ledger)
+	assert.Equal(t, 7, len(transactions))
+	postings := transactions[0].Postings()
+	assert.Equal(t, AccountName("Assets:Invest"), postings[0].Account())
+	assert.Equal(t, Currency("S1"), postings[0].Amount().Currency())
+	assert.True(t, postings[0].AtCost())
+	assert.True(t, postings[0].Price().Value().Equal(decimal.NewFromInt(100)))
+
+	accounts := ls.Accounts()
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, AccountName("Assets:Bank"), accounts[0].Name())
+	assert.Equal(t, []Currency{"EUR"}, accounts[0].Currencies())
+
+	assert.True(t, ls.Balance("Assets:Bank")["EUR"].Equal(decimal.NewFromInt(-102)))
+	ls.Balance("Assets:Bank")["EUR"] = decimal.Zero
+	assert.True(t, ls.Balances()["Assets:Bank"]["EUR"].Equal(decimal.NewFromInt(-102)), "balances are copied")
+
+	inventory := ls.Inventory("Assets:Invest")
+	assert.Equal(t, 1, len(inventory["S2"]))
+	assert.True(t, inventory["S2"][0].Cost().Value().Equal(decimal.NewFromInt(10)))
+	_, ok := inventory["S1"]
+	assert.False(t, ok, "all S1 lots are sold")
+
+	price, ok := ls.Price("S1", time.Date(2000, time.January, 5, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.True(t, price.Value().Equal(decimal.NewFromInt(200)))
+	price, ok = ls.Price("S1", time.Date(2000, time.January, 4, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.True(t, price.Value().Equal(decimal.NewFromInt(100)))
+	_, ok = ls.Price("S1", time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.bean": {Data: []byte("2000-01-01 open Assets:Bank\ninclude \"years/2000.bean\"\n")},
+		"years/2000.bean": {Data: []byte("2000-01-01 open Income:Job\ninclude \"../accounts.bean\"\ninclude \"/root.bean\"\n" +
+			"2000-01-02 *\n  Assets:Bank 10 EUR\n  Income:Job\n")},
+		"accounts.bean": {Data: []byte("2000-01-01 open Expenses:Food\n")},
+		"root.bean":     {Data: []byte("2000-01-01 open Expenses:Rent\n")},
+	}
+	ledger := NewLedger()
+	err := ledger.LoadFS(fsys, "main.bean")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"main.bean", "years/2000.bean", "accounts.bean", "root.bean"}, ledger.Files())
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(ls.Accounts()))
+	assert.True(t, ls.Balance("Income:Job")["EUR"].Equal(decimal.NewFromInt(-10)))
+
+	ledger = NewLedger()
+	err = ledger.LoadFS(fstest.MapFS{"main.bean": {Data: []byte("include \"../outside.bean\"\n")}}, "main.bean")
+	assert.NotNil(t, err)
+}
+
+func TestLoadReader(t *testing.T) {
+	src, err := os.ReadFile("testdata/basic.bean")
+	assert.Nil(t, err)
+	ledger := NewLedger()
+	err = ledger.LoadReader("testdata/basic.bean", strings.NewReader(string(src)))
+	assert.Nil(t, err)
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+	assert.True(t, ls.Balance("Assets:Bank")["EUR"].Equal(decimal.RequireFromString("79.5")))
+}
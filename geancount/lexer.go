@@ -0,0 +1,227 @@
+package geancount
+
+import "unicode/utf8"
+
+// tokenChunk is a number of tokens allocated at once by the lexer
+const tokenChunk = 4096
+
+// byteClass is a class of a byte which starts or breaks a token
+type byteClass uint8
+
+const (
+	classWord byteClass = iota
+	classSpace
+	classNewline
+	classComment
+	classBrace
+	classQuote
+)
+
+var byteClasses = func() [256]byteClass {
+	classes := [256]byteClass{}
+	classes[' '], classes['\t'], classes['\r'] = classSpace, classSpace, classSpace
+	classes['\n'] = classNewline
+	classes[';'] = classComment
+	classes['{'], classes['}'] = classBrace, classBrace
+	classes['"'] = classQuote
+	return classes
+}()
+
+// lexSource splits source into tokens including trivia and classifies words.
+// The source is copied once, texts of tokens are substrings of the copy.
+// Tokens are allocated in chunks to avoid an allocation per token
+func lexSource(src []byte) []*SyntaxToken {
+	s := string(src)
+	tokens := make([]*SyntaxToken, 0, len(s)/4+1)
+	chunk := make([]SyntaxToken, 0, min(tokenChunk, len(s)/4+1))
+	var lineNum, column int32 = 1, 1
+	for i := 0; i < len(s); {
+		start := i
+		// ascii is false if the token contains multi-byte runes
+		ascii := true
+		var kind SyntaxKind
+		switch byteClasses[s[i]] {
+		case classNewline:
+			kind = SyntaxNewline
+			i++
+		case classSpace:
+			kind = SyntaxWhitespace
+			for i < len(s) && byteClasses[s[i]] == classSpace {
+				i++
+			}
+		case classComment:
+			kind = SyntaxComment
+			for i < len(s) && s[i] != '\n' {
+				ascii = ascii && s[i] < utf8.RuneSelf
+				i++
+			}
+		case classBrace:
+			kind = SyntaxBrace
+			i++
+		case classQuote:
+			kind = SyntaxString
+			i++
+			for i < len(s) {
+				ascii = ascii && s[i] < utf8.RuneSelf
+				if s[i] == '\\' && i+1 < len(s) {
+					// The escaped character can not close the string
+					i += 2
+					continue
+				}
+				if s[i] == '"' {
+					i++
+					break
+				}
+				i++
+			}
+		default:
+			for i < len(s) && byteClasses[s[i]] == classWord {
+				ascii = ascii && s[i] < utf8.RuneSelf
+				i++
+			}
+			kind = classifyWord(s[start:i])
+		}
+		text := s[start:i]
+		if len(chunk) == cap(chunk) {
+			chunk = make([]SyntaxToken, 0, tokenChunk)
+		}
+		chunk = append(chunk, SyntaxToken{kind: kind, text: text, lineNum: lineNum, column: column})
+		tokens = append(tokens, &chunk[len(chunk)-1])
+		switch {
+		case kind == SyntaxNewline:
+			lineNum++
+			column = 1
+		case kind == SyntaxString:
+			// Only strings can span over several lines
+			lastNewline := -1
+			for j := 0; j < len(text); j++ {
+				if text[j] == '\n' {
+					lineNum++
+					lastNewline = j
+				}
+			}
+			if lastNewline == -1 {
+				column += runeCount(text, ascii)
+			} else {
+				column = runeCount(text[lastNewline+1:], ascii) + 1
+			}
+		default:
+			column += runeCount(text, ascii)
+		}
+	}
+	return tokens
+}
+
+func runeCount(s string, ascii bool) int32 {
+	if ascii {
+		return int32(len(s))
+	}
+	return int32(utf8.RuneCountInString(s))
+}
+
+// classifyWord returns kind of the word token
+func classifyWord(s string) SyntaxKind {
+	switch c := s[0]; {
+	case c >= '0' && c <= '9':
+		if isDate(s) {
+			return SyntaxDate
+		}
+		if isNumber(s) {
+			return SyntaxNumber
+		}
+	case c == '-' || c == '+' || c == '.':
+		if isNumber(s) {
+			return SyntaxNumber
+		}
+	case c >= 'A' && c <= 'Z':
+		if isAccount(s) {
+			return SyntaxAccount
+		}
+		if isCurrency(s) {
+			return SyntaxCurrency
+		}
+	case len(s) == 1 && (c == '*' || c == '!' || c == '&' || c == '#' || c == '?' || c == '%'):
+		return SyntaxFlag
+	case c == '@' || c == '~':
+		if s == "@" || s == "@@" || s == "~" {
+			return SyntaxPunct
+		}
+	}
+	return SyntaxWord
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isDate checks if s is YYYY-MM-DD
+func isDate(s string) bool {
+	if len(s) != 10 || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+	for _, i := range [...]int{0, 1, 2, 3, 5, 6, 8, 9} {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumber checks if s is a number with optional sign, thousands separators and fraction like -1,000.50
+func isNumber(s string) bool {
+	i := 0
+	if s[0] == '-' || s[0] == '+' {
+		i++
+	}
+	digits := 0
+	if i < len(s) && isDigit(s[i]) {
+		for i < len(s) && (isDigit(s[i]) || s[i] == ',') {
+			if s[i] != ',' {
+				digits++
+			}
+			i++
+		}
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			digits++
+			i++
+		}
+	}
+	return i == len(s) && digits > 0
+}
+
+// isAccount checks if s looks like Assets:Bank, names of components are not checked
+func isAccount(s string) bool {
+	colons := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ':':
+			if s[i-1] == ':' {
+				return false
+			}
+			colons++
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', isDigit(c), c == '-', c >= utf8.RuneSelf:
+		default:
+			return false
+		}
+	}
+	return colons > 0 && s[len(s)-1] != ':'
+}
+
+// isCurrency checks if s is a currency like EUR or VACHR_2024
+func isCurrency(s string) bool {
+	if len(s) > 24 {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z') && !isDigit(c) && c != '\'' && c != '.' && c != '_' && c != '-' {
+			return false
+		}
+	}
+	last := s[len(s)-1]
+	return (last >= 'A' && last <= 'Z') || isDigit(last)
+}
@@ -0,0 +1,120 @@
+package geancount
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// generateLedger returns a ledger with about n lines
+func generateLedger(n int) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString("option \"operating_currency\" \"EUR\"\n")
+	buf.WriteString("2000-01-01 open Assets:Bank:Checking EUR\n")
+	buf.WriteString("2000-01-01 open Assets:Broker STOCK,EUR\n")
+	buf.WriteString("2000-01-01 open Expenses:Food:Groceries\n")
+	buf.WriteString("2000-01-01 open Income:Salary\n\n")
+	for i := 0; i*6 < n; i++ {
+		date := fmt.Sprintf("20%02d-%02d-%02d", 1+i/10000%99, 1+i/28%12, 1+i%28)
+		fmt.Fprintf(&buf, "%s * \"Shop %d\" \"Groceries and more\" ; comment\n", date, i%100)
+		fmt.Fprintf(&buf, "  Expenses:Food:Groceries  %d.%02d EUR\n", i%1000, i%100)
+		buf.WriteString("  Assets:Bank:Checking\n")
+		fmt.Fprintf(&buf, "%s balance Assets:Bank:Checking  -%d.00 EUR\n", date, i)
+		fmt.Fprintf(&buf, "%s price STOCK  1,%03d.50 EUR\n", date, i%1000)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func TestLexSourceKinds(t *testing.T) {
+	src := "2000-01-02 * \"Shop\" ; note\n  Assets:Bank:Счёт  -1,000.50 EUR {2 USD} @ 3 VACHR_2024\n  Expenses:Food @@ .5 A\n"
+	kinds := map[string]SyntaxKind{}
+	for _, token := range lexSource([]byte(src)) {
+		kinds[token.text] = token.kind
+	}
+	assert.Equal(t, map[string]SyntaxKind{
+		"2000-01-02":       SyntaxDate,
+		"*":                SyntaxFlag,
+		" ":                SyntaxWhitespace,
+		"  ":               SyntaxWhitespace,
+		"\"Shop\"":         SyntaxString,
+		"; note":           SyntaxComment,
+		"\n":               SyntaxNewline,
+		"Assets:Bank:Счёт": SyntaxAccount,
+		"-1,000.50":        SyntaxNumber,
+		"EUR":              SyntaxCurrency,
+		"{":                SyntaxBrace,
+		"2":                SyntaxNumber,
+		"USD":              SyntaxCurrency,
+		"}":                SyntaxBrace,
+		"@":                SyntaxPunct,
+		"3":                SyntaxNumber,
+		"VACHR_2024":       SyntaxCurrency,
+		"Expenses:Food":    SyntaxAccount,
+		"@@":               SyntaxPunct,
+		".5":               SyntaxNumber,
+		"A":                SyntaxCurrency,
+	}, kinds)
+
+	for word, kind := range map[string]SyntaxKind{
+		"open":        SyntaxWord,
+		"2000-1-02":   SyntaxWord,
+		"1.2.3":       SyntaxWord,
+		"-":           SyntaxWord,
+		"Assets:":     SyntaxWord,
+		"Assets::Bar": SyntaxWord,
+		"EUR_":        SyntaxWord,
+		"#tag":        SyntaxWord,
+		"1.":          SyntaxNumber,
+	} {
+		assert.Equal(t, kind, classifyWord(word), word)
+	}
+}
+
+// Tokens, lines and nodes are allocated in chunks so there are much less allocations than lines
+func TestParseInputAllocations(t *testing.T) {
+	src := generateLedger(6000)
+	allocs := testing.AllocsPerRun(10, func() {
+		parseInput(bytes.NewReader(src))
+	})
+	assert.Less(t, allocs, float64(bytes.Count(src, []byte("\n")))/50)
+}
+
+// The lexer is expected to keep above 40 MB/s and parseInput above 20 MB/s on a 1M-line ledger.
+// Benchmarks report the throughput in the MB/s column of go test -run ^$ -bench 'LexSource|ParseInput'
+func BenchmarkLexSource(b *testing.B) {
+	src := generateLedger(1_000_000)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for range b.N {
+		lexSource(src)
+	}
+	reportLines(b, src)
+}
+
+func BenchmarkParseInput(b *testing.B) {
+	src := generateLedger(1_000_000)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for range b.N {
+		parseInput(bytes.NewReader(src))
+	}
+	reportLines(b, src)
+}
+
+func BenchmarkLoadReader(b *testing.B) {
+	src := generateLedger(1_000_000)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for range b.N {
+		NewLedger().LoadReader("bench.bean", bytes.NewReader(src))
+	}
+	reportLines(b, src)
+}
+
+func reportLines(b *testing.B, src []byte) {
+	lines := bytes.Count(src, []byte("\n"))
+	b.ReportMetric(float64(lines)*float64(b.N)/b.Elapsed().Seconds(), "lines/s")
+}
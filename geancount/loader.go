@@ -0,0 +1,243 @@
+package geancount
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// fileLoad is a file read and parsed by the loader
+type fileLoad struct {
+	done     chan struct{}
+	parsed   *parsedFile
+	err      error
+	includes []fileInclude
+}
+
+// fileInclude is an include of a file with the resolved name
+type fileInclude struct {
+	name string
+	line Line
+	err  error
+	// pattern is the resolved pattern if the file is matched by it
+	pattern string
+}
+
+// Include is an include of a file by another file
+type Include struct {
+	from    string
+	to      string
+	lineNum int
+}
+
+// From returns name of the including file
+func (i Include) From() string {
+	return i.from
+}
+
+// To returns name of the included file
+func (i Include) To() string {
+	return i.to
+}
+
+// LineNum returns number of the line with the include
+func (i Include) LineNum() int {
+	return i.lineNum
+}
+
+// loader reads and parses files concurrently. Includes are scheduled as soon as the including file
+// is parsed, the number of files processed at once is limited by the number of workers
+type loader struct {
+	l  *Ledger
+	mu sync.Mutex
+	// loads are files by key of their names
+	loads map[string]*fileLoad
+	sem   chan struct{}
+	// merged are include chains of merged files by key
+	merged map[string][]string
+}
+
+func newLoader(l *Ledger) *loader {
+	workers := l.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &loader{l: l, loads: map[string]*fileLoad{}, sem: make(chan struct{}, workers), merged: map[string][]string{}}
+}
+
+// load reads the root file with all its includes and adds their directives to the ledger.
+// If r is not nil it is used as the content of the root file
+func (l *Ledger) load(root string, r io.Reader) error {
+	ld := newLoader(l)
+	var read func() ([]byte, error)
+	if r != nil {
+		read = func() ([]byte, error) {
+			return io.ReadAll(r)
+		}
+	}
+	ld.schedule(root, read)
+	dirs := len(l.documentDirs)
+	err := ld.merge(root, []string{root})
+	err = errors.Join(err, l.discoverDocuments(l.documentDirs[dirs:]))
+	l.sortDirectives()
+	warnings, err := splitWarnings(err)
+	l.warnings = append(l.warnings, warnings...)
+	return err
+}
+
+// schedule starts loading of the file if it is not started yet, files are read from fsys if read is nil
+func (ld *loader) schedule(name string, read func() ([]byte, error)) {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+	key := ld.l.fsys.key(name)
+	if _, ok := ld.loads[key]; ok {
+		return
+	}
+	load := &fileLoad{done: make(chan struct{})}
+	ld.loads[key] = load
+	go func() {
+		ld.sem <- struct{}{}
+		defer func() { <-ld.sem }()
+		defer close(load.done)
+		ld.run(name, read, load)
+	}()
+}
+
+func (ld *loader) run(name string, read func() ([]byte, error), load *fileLoad) {
+	if read == nil {
+		read = func() ([]byte, error) {
+			return ld.readFile(name)
+		}
+	}
+	src, err := read()
+	if err != nil {
+		load.err = err
+		return
+	}
+	load.parsed, load.err = ld.l.parseFile(name, src)
+	if load.err != nil {
+		return
+	}
+	for _, lg := range load.parsed.includes {
+		line := lg.lines[0]
+		if len(line.tokens) < 2 {
+			continue
+		}
+		load.includes = append(load.includes, ld.resolve(name, line)...)
+	}
+}
+
+// resolve returns files included by the line and schedules their loading.
+// A pattern includes all matching files in lexical order except the including file itself
+func (ld *loader) resolve(name string, line Line) []fileInclude {
+	fsys := ld.l.fsys
+	pattern := line.tokens[1].text
+	resolved, err := fsys.resolve(name, pattern)
+	if err != nil {
+		return []fileInclude{{line: line, err: newDiagnostic(CodeInclude, "%s", err).at(tokenSpan(line, 1)).withFile(name)}}
+	}
+	if !isGlob(pattern) {
+		ld.schedule(resolved, nil)
+		return []fileInclude{{name: resolved, line: line}}
+	}
+	matches, err := fsys.glob(resolved)
+	if err != nil {
+		err = newDiagnostic(CodeInclude, "invalid pattern %s: %s", pattern, err).at(tokenSpan(line, 1)).withFile(name)
+		return []fileInclude{{line: line, pattern: resolved, err: err}}
+	}
+	includes := []fileInclude{}
+	for _, match := range matches {
+		if fsys.key(match) == fsys.key(name) {
+			continue
+		}
+		ld.schedule(match, nil)
+		includes = append(includes, fileInclude{name: match, line: line, pattern: resolved})
+	}
+	if len(includes) == 0 {
+		err = newDiagnostic(CodeInclude, "no files match %s", pattern).at(tokenSpan(line, 1)).withFile(name)
+		return []fileInclude{{line: line, pattern: resolved, err: err}}
+	}
+	return includes
+}
+
+func (ld *loader) readFile(name string) ([]byte, error) {
+	file, err := ld.l.fsys.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// merge adds the file and its includes to the ledger in the order of a sequential load:
+// includes first in order they appear in the file, then directives of the file itself.
+// chain is names of files from the root to the file, a file is merged only once
+func (ld *loader) merge(name string, chain []string) error {
+	l := ld.l
+	if !slices.Contains(l.files, name) {
+		l.files = append(l.files, name)
+	}
+	key := l.fsys.key(name)
+	ld.mu.Lock()
+	load := ld.loads[key]
+	ld.mu.Unlock()
+	<-load.done
+	if load.err != nil {
+		// Every include of a file which can not be read gets the error
+		return load.err
+	}
+	ld.merged[key] = chain
+	if l.cache != nil {
+		l.parsed[name] = load.parsed
+	}
+	l.operatingCurrencies = append(l.operatingCurrencies, load.parsed.operatingCurrencies...)
+	errs := []error{load.parsed.err}
+	for _, line := range load.parsed.documents {
+		dir, err := l.fsys.resolve(name, line.tokens[2].text)
+		if err != nil {
+			errs = append(errs, newDiagnostic(CodeDocumentMissing, "%s", err).at(tokenSpan(line, 2)).withFile(name))
+			continue
+		}
+		l.documentDirs = append(l.documentDirs, documentDir{name: dir, fileName: name, line: line})
+	}
+	for _, include := range load.includes {
+		if include.pattern != "" && !slices.Contains(l.patterns, include.pattern) {
+			l.patterns = append(l.patterns, include.pattern)
+		}
+		if include.err != nil {
+			errs = append(errs, include.err)
+			continue
+		}
+		l.includes = append(l.includes, Include{from: name, to: include.name, lineNum: include.line.lineNum})
+		includeChain := append(slices.Clone(chain), include.name)
+		includeKey := l.fsys.key(include.name)
+		at := tokenSpan(include.line, 1)
+		if i := slices.IndexFunc(chain, func(f string) bool { return l.fsys.key(f) == includeKey }); i != -1 {
+			errs = append(errs, newDiagnostic(CodeIncludeCycle, "include cycle: %s", strings.Join(includeChain[i:], " -> ")).
+				at(at).withFile(name))
+			continue
+		}
+		if first, ok := ld.merged[includeKey]; ok {
+			errs = append(errs, newDiagnostic(CodeDuplicateInclude, "%s is already included: %s",
+				include.line.tokens[1].text, strings.Join(first, " -> ")).at(at).withFile(name))
+			continue
+		}
+		err := ld.merge(include.name, includeChain)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = newDiagnostic(CodeInclude, "can not include %s: %s", include.line.tokens[1].text, pathErr.Err).
+				at(at).withFile(name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	start := len(l.directives)
+	l.directives = append(l.directives, load.parsed.directives...)
+	errs = append(errs, l.resolveDocuments(name, l.directives[start:])...)
+	return errors.Join(errs...)
+}
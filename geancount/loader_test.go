@@ -0,0 +1,152 @@
+package geancount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadIncludesConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	main := "option \"operating_currency\" \"EUR\"\n2000-01-01 open Assets:Bank\n2000-01-01 open Income:Job\n"
+	for year := 2000; year < 2040; year++ {
+		main += fmt.Sprintf("include \"%d.bean\"\n", year)
+		src := fmt.Sprintf("include \"accounts/%d.bean\"\n", year)
+		// The same date in every file checks that the order of included files is kept
+		src += fmt.Sprintf("2000-01-02 * \"Salary %d\"\n  Assets:Bank 1 EUR\n  Income:Job\n", year)
+		if year%10 == 0 {
+			src += "2000-01-03 open Assets:Bank\ninclude \"missing.bean\"\n"
+		}
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.bean", year)), []byte(src), 0o644)
+		os.MkdirAll(filepath.Join(dir, "accounts"), 0o755)
+		os.WriteFile(filepath.Join(dir, "accounts", fmt.Sprintf("%d.bean", year)),
+			[]byte(fmt.Sprintf("option \"operating_currency\" \"C%d\"\n2000-01-02 open Expenses:Y%d\n", year, year)), 0o644)
+	}
+	os.WriteFile(filepath.Join(dir, "main.bean"), []byte(main), 0o644)
+
+	load := func(workers int) (*Ledger, []Diagnostic) {
+		ledger := NewLedger()
+		ledger.workers = workers
+		err := ledger.LoadFile(filepath.Join(dir, "main.bean"))
+		return ledger, Diagnostics(err)
+	}
+	sequential, diagnostics := load(1)
+	assert.Equal(t, 82, len(sequential.Files()))
+	assert.Equal(t, filepath.Join(dir, "2000.bean"), sequential.Files()[1])
+	assert.Equal(t, filepath.Join(dir, "accounts", "2000.bean"), sequential.Files()[2])
+	assert.Equal(t, []Currency{"EUR", "C2000", "C2001"}, sequential.OperatingCurrencies()[:3])
+	assert.Equal(t, 4, len(diagnostics))
+	transactions := DirectivesOf[Transaction](sequential)
+	assert.Equal(t, "Salary 2000", transactions[0].Narration())
+	assert.Equal(t, "Salary 2039", transactions[39].Narration())
+
+	for range 5 {
+		parallel, parallelDiagnostics := load(8)
+		assert.Equal(t, sequential.Files(), parallel.Files())
+		assert.Equal(t, sequential.OperatingCurrencies(), parallel.OperatingCurrencies())
+		assert.Equal(t, sequential.Directives(), parallel.Directives())
+		assert.Equal(t, diagnostics, parallelDiagnostics)
+	}
+}
+
+func TestLoadGlobIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.bean":          {Data: []byte("include \"accounts/*.bean\"\ninclude \"*.bean\"\ninclude \"prices/*.bean\"\n")},
+		"accounts/c.bean":    {Data: []byte("2000-01-01 open Assets:C\n")},
+		"accounts/a.bean":    {Data: []byte("2000-01-01 open Assets:A\n")},
+		"accounts/b.bean":    {Data: []byte("2000-01-01 open Assets:B\n")},
+		"accounts/notes.txt": {Data: []byte("not a ledger\n")},
+		"other.bean":         {Data: []byte("2000-01-01 open Assets:Other\n")},
+	}
+	ledger := NewLedger()
+	diagnostics := Diagnostics(ledger.LoadFS(fsys, "main.bean"))
+	assert.Equal(t, []string{"main.bean", "accounts/a.bean", "accounts/b.bean", "accounts/c.bean", "other.bean"}, ledger.Files())
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, CodeInclude, diagnostics[0].Code)
+	assert.Equal(t, "no files match prices/*.bean", diagnostics[0].Message)
+	assert.Equal(t, []Include{
+		{from: "main.bean", to: "accounts/a.bean", lineNum: 1},
+		{from: "main.bean", to: "accounts/b.bean", lineNum: 1},
+		{from: "main.bean", to: "accounts/c.bean", lineNum: 1},
+		{from: "main.bean", to: "other.bean", lineNum: 2},
+	}, ledger.Includes())
+	opens := DirectivesOf[AccountOpen](ledger)
+	assert.Equal(t, AccountName("Assets:A"), opens[0].Account())
+	assert.Equal(t, AccountName("Assets:Other"), opens[3].Account())
+}
+
+func TestIncludeCycles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.bean":   {Data: []byte("include \"a.bean\"\ninclude \"b.bean\"\n")},
+		"a.bean":      {Data: []byte("include \"sub/c.bean\"\n2000-01-01 open Assets:A\n")},
+		"b.bean":      {Data: []byte("2000-01-01 open Assets:B\n")},
+		"sub/c.bean":  {Data: []byte("include \"../a.bean\"\ninclude \"/b.bean\"\ninclude \"../main.bean\"\n")},
+		"self.bean":   {Data: []byte("include \"self.bean\"\n")},
+		"twice.bean":  {Data: []byte("include \"b.bean\"\ninclude \"./b.bean\"\n")},
+		"unused.bean": {Data: []byte("")},
+	}
+	ledger := NewLedger()
+	diagnostics := Diagnostics(ledger.LoadFS(fsys, "main.bean"))
+	assert.Equal(t, []string{"main.bean", "a.bean", "sub/c.bean", "b.bean"}, ledger.Files())
+	assert.Equal(t, 3, len(diagnostics))
+	// b.bean is included by c.bean first, so the include from main.bean is the duplicate
+	assert.Equal(t, CodeDuplicateInclude, diagnostics[0].Code)
+	assert.Equal(t, Location{FileName: "main.bean", Line: 2, Column: 9, EndColumn: 17}, diagnostics[0].Location)
+	assert.Equal(t, "b.bean is already included: main.bean -> a.bean -> sub/c.bean -> b.bean", diagnostics[0].Message)
+	assert.Equal(t, CodeIncludeCycle, diagnostics[1].Code)
+	assert.Equal(t, "sub/c.bean", diagnostics[1].Location.FileName)
+	assert.Equal(t, "include cycle: a.bean -> sub/c.bean -> a.bean", diagnostics[1].Message)
+	assert.Equal(t, CodeIncludeCycle, diagnostics[2].Code)
+	assert.Equal(t, "include cycle: main.bean -> a.bean -> sub/c.bean -> main.bean", diagnostics[2].Message)
+	assert.Equal(t, 2, len(DirectivesOf[AccountOpen](ledger)))
+	assert.Equal(t, 6, len(ledger.Includes()))
+
+	ledger = NewLedger()
+	diagnostics = Diagnostics(ledger.LoadFS(fsys, "self.bean"))
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, "include cycle: self.bean -> self.bean", diagnostics[0].Message)
+
+	ledger = NewLedger()
+	diagnostics = Diagnostics(ledger.LoadFS(fsys, "twice.bean"))
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, CodeDuplicateInclude, diagnostics[0].Code)
+	assert.Equal(t, 1, len(DirectivesOf[AccountOpen](ledger)))
+}
+
+func TestIncludeCycleWithRelativeRoot(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.bean"), []byte("include \"other.bean\"\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "other.bean"), []byte("include \"main.bean\"\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	ledger := NewLedger()
+	diagnostics := Diagnostics(ledger.LoadFile("main.bean"))
+	assert.Equal(t, 2, len(ledger.Files()))
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, CodeIncludeCycle, diagnostics[0].Code)
+}
+
+func TestWatchGlobIncludes(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.bean")
+	os.WriteFile(main, []byte("include \"accounts/*.bean\"\n"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "accounts"), 0o755)
+	os.WriteFile(filepath.Join(dir, "accounts", "a.bean"), []byte("2000-01-01 open Assets:A\n"), 0o644)
+
+	w := newLedgerWatcher(main, nil)
+	assert.False(t, w.changed())
+	os.WriteFile(filepath.Join(dir, "accounts", "b.bean"), []byte("2000-01-01 open Assets:B\n"), 0o644)
+	accounts := filepath.Join(dir, "accounts")
+	os.Chtimes(accounts, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	assert.True(t, w.changed())
+	s := w.reload()
+	assert.Nil(t, s.err)
+	assert.Equal(t, 2, len(s.state.Accounts()))
+}
@@ -0,0 +1,662 @@
+package geancount
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds used in LSP messages, see the specification of the protocol
+const (
+	lspSyncFull           = 1
+	lspCompletionModule   = 9
+	lspCompletionConstant = 21
+	lspErrMethodNotFound  = -32601
+	lspErrInvalidParams   = -32602
+)
+
+var accountRoots = []string{"Assets", "Liabilities", "Equity", "Income", "Expenses"}
+
+// LSPServer is a Language Server Protocol server for ledger files talking JSON-RPC over a stream.
+// Diagnostics are published when a file is opened or saved, open documents are used for
+// completion, hover and formatting
+type LSPServer struct {
+	root      string
+	in        *bufio.Reader
+	out       io.Writer
+	watcher   *ledgerWatcher
+	documents map[string]string
+	// trees are syntax trees of documents by path with the text they are parsed from
+	trees map[string]lspSyntaxTree
+	// published are files which have diagnostics shown in the editor
+	published map[string]bool
+}
+
+type lspSyntaxTree struct {
+	text string
+	tree *SyntaxTree
+}
+
+type lspRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *lspError) Error() string {
+	return e.Message
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument   lspTextDocument `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspFormattingParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Options      struct {
+		TabSize      int  `json:"tabSize"`
+		InsertSpaces bool `json:"insertSpaces"`
+	} `json:"options"`
+}
+
+type lspDiagnostic struct {
+	Range              lspRange                `json:"range"`
+	Severity           int                     `json:"severity"`
+	Code               string                  `json:"code,omitempty"`
+	Source             string                  `json:"source"`
+	Message            string                  `json:"message"`
+	RelatedInformation []lspRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type lspRelatedInformation struct {
+	Location lspLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+type lspPublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCompletionItem struct {
+	Label    string      `json:"label"`
+	Kind     int         `json:"kind"`
+	Detail   string      `json:"detail,omitempty"`
+	TextEdit lspTextEdit `json:"textEdit"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    lspRange         `json:"range"`
+}
+
+// NewLSPServer creates LSPServer reading requests from in and writing responses to out.
+// root is the main ledger file, if it is empty the first opened document is used
+func NewLSPServer(root string, in io.Reader, out io.Writer) *LSPServer {
+	if root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+	}
+	return &LSPServer{
+		root:      root,
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]string{},
+		trees:     map[string]lspSyntaxTree{},
+		published: map[string]bool{},
+	}
+}
+
+// Run handles requests until exit notification is received or input is closed
+func (s *LSPServer) Run() error {
+	for {
+		req, err := s.readMessage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		if err := s.respond(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LSPServer) readMessage() (lspRequest, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return lspRequest{}, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return lspRequest{}, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return lspRequest{}, err
+	}
+	req := lspRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return lspRequest{}, err
+	}
+	return req, nil
+}
+
+func (s *LSPServer) write(message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *LSPServer) respond(id json.RawMessage, result any, err error) error {
+	resp := lspResponse{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		var lspErr *lspError
+		if !errors.As(err, &lspErr) {
+			lspErr = &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		resp.Error = lspErr
+		return s.write(resp)
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	resp.Result = body
+	return s.write(resp)
+}
+
+func (s *LSPServer) notify(method string, params any) error {
+	return s.write(lspNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle processes the request and returns its result, results of notifications are ignored
+func (s *LSPServer) handle(req lspRequest) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    lspSyncFull,
+					"save":      map[string]any{"includeText": false},
+				},
+				"completionProvider":         map[string]any{"triggerCharacters": []string{":"}},
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "geancount"},
+		}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		params := struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		path := uriToPath(params.TextDocument.URI)
+		s.documents[path] = params.TextDocument.Text
+		if s.root == "" {
+			s.root = path
+		}
+		if s.watcher == nil {
+			s.watcher = newLedgerWatcher(s.root, nil)
+			return nil, s.publishDiagnostics()
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		params := lspDidChangeParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.documents[uriToPath(params.TextDocument.URI)] = params.ContentChanges[n-1].Text
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		if s.watcher == nil {
+			return nil, nil
+		}
+		s.watcher.reload()
+		return nil, s.publishDiagnostics()
+	case "textDocument/didClose":
+		params := lspPositionParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, uriToPath(params.TextDocument.URI))
+		return nil, nil
+	case "textDocument/completion":
+		params := lspPositionParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/definition":
+		params := lspPositionParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		params := lspPositionParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/formatting":
+		params := lspFormattingParams{}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.formatting(params), nil
+	}
+	return nil, &lspError{Code: lspErrMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// publishDiagnostics sends diagnostics of the current snapshot for every loaded file.
+// Files which had diagnostics before get an empty list so the editor clears them
+func (s *LSPServer) publishDiagnostics() error {
+	snapshot := s.watcher.snapshot.Load()
+	byFile := map[string][]lspDiagnostic{}
+	for f := range s.published {
+		byFile[f] = []lspDiagnostic{}
+	}
+	for _, f := range snapshot.ledger.files {
+		byFile[f] = []lspDiagnostic{}
+	}
+	diagnostics := append(Diagnostics(snapshot.err), snapshot.ledger.Warnings()...)
+	for _, d := range diagnostics {
+		if d.FileName == "" {
+			d.FileName = s.root
+		}
+		byFile[d.FileName] = append(byFile[d.FileName], s.lspDiagnostic(d))
+	}
+	files := make([]string, 0, len(byFile))
+	for f := range byFile {
+		files = append(files, f)
+	}
+	slices.Sort(files)
+	s.published = map[string]bool{}
+	for _, f := range files {
+		err := s.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsParams{URI: pathToURI(f), Diagnostics: byFile[f]})
+		if err != nil {
+			return err
+		}
+		if len(byFile[f]) > 0 {
+			s.published[f] = true
+		}
+	}
+	return nil
+}
+
+func (s *LSPServer) lspDiagnostic(d Diagnostic) lspDiagnostic {
+	severity := 3
+	switch d.Severity {
+	case SeverityError:
+		severity = 1
+	case SeverityWarning:
+		severity = 2
+	}
+	message := d.Message
+	if d.Hint != "" {
+		message += "\n" + d.Hint
+	}
+	diagnostic := lspDiagnostic{
+		Range:    s.locationRange(d.Location),
+		Severity: severity,
+		Code:     d.Code,
+		Source:   "geancount",
+		Message:  message,
+	}
+	for _, r := range d.Related {
+		diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, lspRelatedInformation{
+			Location: lspLocation{URI: pathToURI(r.FileName), Range: s.locationRange(r.Location)},
+			Message:  r.Message,
+		})
+	}
+	return diagnostic
+}
+
+// locationRange converts Location to LSP range, a location without columns covers the whole line
+func (s *LSPServer) locationRange(l Location) lspRange {
+	if l.Line == 0 {
+		return lspRange{}
+	}
+	line := s.lineText(l.FileName, l.Line-1)
+	if l.Column == 0 {
+		return lspRange{Start: lspPosition{Line: l.Line - 1}, End: lspPosition{Line: l.Line - 1, Character: utf16Offset(line, len([]rune(line))+1)}}
+	}
+	return lspRange{
+		Start: lspPosition{Line: l.Line - 1, Character: utf16Offset(line, l.Column)},
+		End:   lspPosition{Line: l.Line - 1, Character: utf16Offset(line, l.EndColumn)},
+	}
+}
+
+// documentText returns text of the open document or content of the file
+func (s *LSPServer) documentText(path string) string {
+	if text, ok := s.documents[path]; ok {
+		return text
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(src)
+}
+
+// lineText returns line of the document, the first line is 0
+func (s *LSPServer) lineText(path string, line int) string {
+	lines := strings.Split(s.documentText(path), "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[line], "\r")
+}
+
+// tokenAt returns code token under the position and code tokens of the line before it.
+// Token is nil if the position is on whitespace
+func (s *LSPServer) tokenAt(path string, pos lspPosition) (*SyntaxToken, []*SyntaxToken) {
+	line := s.lineText(path, pos.Line)
+	column := runeColumn(line, pos.Character)
+	before := []*SyntaxToken{}
+	for _, t := range lexSource([]byte(line)) {
+		if t.IsTrivia() {
+			continue
+		}
+		end := t.Column() + len([]rune(t.text))
+		if t.Column() <= column && column <= end {
+			return t, before
+		}
+		if end < column {
+			before = append(before, t)
+		}
+	}
+	return nil, before
+}
+
+// tokenRange returns range of the token on the line
+func tokenRange(line string, lineNum int, t *SyntaxToken) lspRange {
+	return lspRange{
+		Start: lspPosition{Line: lineNum, Character: utf16Offset(line, t.Column())},
+		End:   lspPosition{Line: lineNum, Character: utf16Offset(line, t.Column()+len([]rune(t.text)))},
+	}
+}
+
+// syntaxTree returns the syntax tree of the document, it is parsed again only if the text is changed
+func (s *LSPServer) syntaxTree(path string) *SyntaxTree {
+	text := s.documentText(path)
+	if t, ok := s.trees[path]; ok && t.text == text {
+		return t.tree
+	}
+	tree := ParseSyntaxTree([]byte(text))
+	s.trees[path] = lspSyntaxTree{text: text, tree: tree}
+	return tree
+}
+
+// directiveDate returns date of the directive containing the line of the document
+func (s *LSPServer) directiveDate(path string, line int) (time.Time, bool) {
+	for _, node := range s.syntaxTree(path).Directives() {
+		lines := node.DirectiveLines()
+		if line+1 < lines[0].LineNum() || line+1 > lines[len(lines)-1].lastLineNum() {
+			continue
+		}
+		date, err := parseDate(lines[0].CodeTokens()[0].Value())
+		return date, err == nil
+	}
+	return time.Time{}, false
+}
+
+func (s *LSPServer) completion(params lspPositionParams) []lspCompletionItem {
+	items := []lspCompletionItem{}
+	if s.watcher == nil {
+		return items
+	}
+	path := uriToPath(params.TextDocument.URI)
+	line := s.lineText(path, params.Position.Line)
+	token, before := s.tokenAt(path, params.Position)
+	edit := lspRange{Start: params.Position, End: params.Position}
+	if token != nil {
+		edit = tokenRange(line, params.Position.Line, token)
+	}
+	snapshot := s.watcher.snapshot.Load()
+	// Currency follows the number of an amount
+	if n := len(before); n > 0 && before[n-1].kind == SyntaxNumber {
+		currencies := map[Currency]struct{}{}
+		for _, c := range snapshot.ledger.operatingCurrencies {
+			currencies[c] = struct{}{}
+		}
+		for _, acc := range snapshot.state.accounts {
+			for c := range acc.currencies {
+				currencies[c] = struct{}{}
+			}
+		}
+		for _, c := range sortedCurrencies(currencies) {
+			items = append(items, lspCompletionItem{Label: string(c), Kind: lspCompletionConstant, TextEdit: lspTextEdit{Range: edit, NewText: string(c)}})
+		}
+		return items
+	}
+	seen := map[AccountName]bool{}
+	for _, open := range DirectivesOf[AccountOpen](snapshot.ledger) {
+		if seen[open.account] {
+			continue
+		}
+		seen[open.account] = true
+		items = append(items, lspCompletionItem{
+			Label:    string(open.account),
+			Kind:     lspCompletionModule,
+			Detail:   "opened " + formatDate(open.date),
+			TextEdit: lspTextEdit{Range: edit, NewText: string(open.account)},
+		})
+	}
+	slices.SortFunc(items, func(a, b lspCompletionItem) int {
+		return strings.Compare(a.Label, b.Label)
+	})
+	return items
+}
+
+// accountAt returns account name under the position
+func (s *LSPServer) accountAt(params lspPositionParams) (AccountName, *SyntaxToken, bool) {
+	token, _ := s.tokenAt(uriToPath(params.TextDocument.URI), params.Position)
+	if token == nil || token.kind != SyntaxAccount || !isAccountName(token.text) {
+		return "", nil, false
+	}
+	return AccountName(token.text), token, true
+}
+
+func (s *LSPServer) definition(params lspPositionParams) []lspLocation {
+	locations := []lspLocation{}
+	account, _, ok := s.accountAt(params)
+	if !ok || s.watcher == nil {
+		return locations
+	}
+	for _, open := range DirectivesOf[AccountOpen](s.watcher.snapshot.Load().ledger) {
+		if open.account == account {
+			locations = append(locations, lspLocation{
+				URI:   pathToURI(open.fileName),
+				Range: s.locationRange(open.accountSpan.location(open.fileName)),
+			})
+		}
+	}
+	return locations
+}
+
+// hover shows balance of the account at the end of the date of the directive under the position
+func (s *LSPServer) hover(params lspPositionParams) *lspHover {
+	account, token, ok := s.accountAt(params)
+	if !ok || s.watcher == nil {
+		return nil
+	}
+	path := uriToPath(params.TextDocument.URI)
+	snapshot := s.watcher.snapshot.Load()
+	ls := snapshot.state
+	heading := "Balance"
+	if date, ok := s.directiveDate(path, params.Position.Line); ok {
+		ls = snapshot.ledger.stateAt(date)
+		heading = "Balance at the end of " + formatDate(date)
+	}
+	if _, ok := ls.accounts[account]; !ok {
+		return nil
+	}
+	amounts := formatCurrenciesAmounts(ls.balances[account])
+	if len(amounts) == 0 {
+		amounts = []string{"0"}
+	}
+	value := fmt.Sprintf("**%s**\n\n%s:\n\n```\n%s\n```", account, heading, strings.Join(amounts, "\n"))
+	line := s.lineText(path, params.Position.Line)
+	return &lspHover{
+		Contents: lspMarkupContent{Kind: "markdown", Value: value},
+		Range:    tokenRange(line, params.Position.Line, token),
+	}
+}
+
+// formatting replaces the whole document if Format changes it
+func (s *LSPServer) formatting(params lspFormattingParams) []lspTextEdit {
+	text := s.documentText(uriToPath(params.TextDocument.URI))
+	opts := DefaultFormatOptions
+	if params.Options.InsertSpaces && params.Options.TabSize > 0 {
+		opts.Indent = params.Options.TabSize
+	}
+	formatted := string(Format([]byte(text), opts))
+	if formatted == text {
+		return []lspTextEdit{}
+	}
+	// The range ends after the last line which has no newline at the end of the document
+	lastLine := text[strings.LastIndex(text, "\n")+1:]
+	end := lspPosition{Line: strings.Count(text, "\n"), Character: utf16Offset(lastLine, len([]rune(lastLine))+1)}
+	return []lspTextEdit{{Range: lspRange{End: end}, NewText: formatted}}
+}
+
+func isAccountName(s string) bool {
+	root, rest, ok := strings.Cut(s, ":")
+	return ok && rest != "" && slices.Contains(accountRoots, root)
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// utf16Offset converts column in runes starting from 1 to offset in UTF-16 code units used by LSP
+func utf16Offset(line string, column int) int {
+	offset := 0
+	runes := []rune(line)
+	for i := 0; i < column-1; i++ {
+		if i < len(runes) && runes[i] >= 0x10000 {
+			offset += 2
+		} else {
+			offset++
+		}
+	}
+	return offset
+}
+
+// runeColumn converts offset in UTF-16 code units to column in runes starting from 1
+func runeColumn(line string, offset int) int {
+	column := 1
+	for _, r := range line {
+		if offset <= 0 {
+			break
+		}
+		if r >= 0x10000 {
+			offset -= 2
+		} else {
+			offset--
+		}
+		column++
+	}
+	return column + max(0, offset)
+}
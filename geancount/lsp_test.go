@@ -0,0 +1,165 @@
+package geancount
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lspTestMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *lspError       `json:"error"`
+}
+
+func writeLSPMessage(buf *bytes.Buffer, id int, method string, params any) {
+	message := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		message["id"] = id
+	}
+	body, _ := json.Marshal(message)
+	fmt.Fprintf(buf, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func readLSPMessages(t *testing.T, out *bytes.Buffer) []lspTestMessage {
+	messages := []lspTestMessage{}
+	r := bufio.NewReader(out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			break
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		assert.Nil(t, err)
+		message := lspTestMessage{}
+		assert.Nil(t, json.Unmarshal(body, &message))
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestLSPServer(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.bean")
+	src := "2000-01-01 open Assets:Bank EUR\n" +
+		"2000-01-01 open Income:Job\n" +
+		"2000-01-02 * \"Salary\"\n" +
+		"  Assets:Bank 100 EUR\n" +
+		"  Income:Job\n" +
+		"2000-01-03 * \"Salary\"\n" +
+		"  Assets:Bank 50 EUR\n" +
+		"  Income:Job\n" +
+		"2000-01-04 balance Assets:Bank 10 EUR\n" +
+		"2000-01-05 * \"Cash\"\n" +
+		"  Assets:Bank 1 E\n" +
+		"  Assets:\n"
+	os.WriteFile(main, []byte(src), 0o644)
+	uri := pathToURI(main)
+	doc := map[string]any{"uri": uri}
+
+	in := bytes.Buffer{}
+	writeLSPMessage(&in, 1, "initialize", map[string]any{})
+	writeLSPMessage(&in, 0, "initialized", map[string]any{})
+	writeLSPMessage(&in, 0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": src}})
+	writeLSPMessage(&in, 2, "textDocument/hover", map[string]any{"textDocument": doc, "position": map[string]int{"line": 3, "character": 4}})
+	writeLSPMessage(&in, 3, "textDocument/definition", map[string]any{"textDocument": doc, "position": map[string]int{"line": 6, "character": 2}})
+	writeLSPMessage(&in, 4, "textDocument/completion", map[string]any{"textDocument": doc, "position": map[string]int{"line": 11, "character": 9}})
+	writeLSPMessage(&in, 5, "textDocument/completion", map[string]any{"textDocument": doc, "position": map[string]int{"line": 10, "character": 17}})
+	writeLSPMessage(&in, 6, "textDocument/formatting", map[string]any{"textDocument": doc, "options": map[string]any{"tabSize": 2, "insertSpaces": true}})
+	writeLSPMessage(&in, 7, "unknown/method", map[string]any{})
+	writeLSPMessage(&in, 8, "shutdown", nil)
+	writeLSPMessage(&in, 0, "exit", nil)
+	out := bytes.Buffer{}
+	assert.Nil(t, NewLSPServer("", &in, &out).Run())
+
+	messages := readLSPMessages(t, &out)
+	assert.Equal(t, 9, len(messages))
+
+	assert.Equal(t, 1, *messages[0].ID)
+	assert.True(t, strings.Contains(string(messages[0].Result), `"hoverProvider":true`))
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1].Method)
+	diagnostics := lspPublishDiagnosticsParams{}
+	json.Unmarshal(messages[1].Params, &diagnostics)
+	assert.Equal(t, uri, diagnostics.URI)
+	assert.Equal(t, 2, len(diagnostics.Diagnostics))
+	assert.Equal(t, CodeBalanceMismatch, diagnostics.Diagnostics[0].Code)
+	assert.Equal(t, CodeCurrencyNotAllowed, diagnostics.Diagnostics[1].Code)
+	assert.Equal(t, lspRange{Start: lspPosition{8, 31}, End: lspPosition{8, 37}}, diagnostics.Diagnostics[0].Range)
+
+	hover := lspHover{}
+	json.Unmarshal(messages[2].Result, &hover)
+	assert.Equal(t, "**Assets:Bank**\n\nBalance at the end of 2000-01-02:\n\n```\n100.00 EUR\n```", hover.Contents.Value)
+	assert.Equal(t, lspRange{Start: lspPosition{3, 2}, End: lspPosition{3, 13}}, hover.Range)
+
+	locations := []lspLocation{}
+	json.Unmarshal(messages[3].Result, &locations)
+	assert.Equal(t, []lspLocation{{URI: uri, Range: lspRange{Start: lspPosition{0, 16}, End: lspPosition{0, 27}}}}, locations)
+
+	items := []lspCompletionItem{}
+	json.Unmarshal(messages[4].Result, &items)
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, "Assets:Bank", items[0].Label)
+	assert.Equal(t, lspRange{Start: lspPosition{11, 2}, End: lspPosition{11, 9}}, items[0].TextEdit.Range)
+
+	items = []lspCompletionItem{}
+	json.Unmarshal(messages[5].Result, &items)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "EUR", items[0].Label)
+
+	edits := []lspTextEdit{}
+	json.Unmarshal(messages[6].Result, &edits)
+	assert.Equal(t, 1, len(edits))
+	assert.Equal(t, string(Format([]byte(src), DefaultFormatOptions)), edits[0].NewText)
+
+	assert.Equal(t, lspErrMethodNotFound, messages[7].Error.Code)
+	assert.Equal(t, "null", string(messages[8].Result))
+}
+
+func TestUTF16Offset(t *testing.T) {
+	line := "a€😀b"
+	assert.Equal(t, 0, utf16Offset(line, 1))
+	assert.Equal(t, 2, utf16Offset(line, 3))
+	assert.Equal(t, 4, utf16Offset(line, 4))
+	assert.Equal(t, 4, runeColumn(line, 4))
+	assert.Equal(t, 5, runeColumn(line, 5))
+}
+
+func TestLSPFormattingWithoutFinalNewline(t *testing.T) {
+	for text, end := range map[string]lspPosition{
+		"2000-01-01 open Assets:Bank\n2000-01-02 balance Assets:Bank 0 EUR":           {Line: 1, Character: 36},
+		"2000-01-01 open Assets:Bank\n2000-01-02 * \"Cafe\"\n  Assets:Bank 1 EUR ; 😀": {Line: 2, Character: 24},
+		"2000-01-01 open Assets:Bank\n2000-01-02 balance Assets:Bank 0 EUR\n":         {Line: 2, Character: 0},
+	} {
+		s := NewLSPServer("", nil, nil)
+		s.documents["main.bean"] = text
+		edits := s.formatting(lspFormattingParams{TextDocument: lspTextDocument{URI: "main.bean"}})
+		if !assert.Equal(t, 1, len(edits), text) {
+			continue
+		}
+		// The edit replaces the whole document
+		assert.Equal(t, lspRange{End: end}, edits[0].Range, text)
+		assert.Equal(t, string(Format([]byte(text), DefaultFormatOptions)), edits[0].NewText)
+	}
+}
+
+func TestLSPLocationRangeOfWholeLine(t *testing.T) {
+	s := NewLSPServer("", strings.NewReader(""), io.Discard)
+	s.documents["main.bean"] = "2000-01-01 * \"Café 😀\"\n"
+	r := s.locationRange(Location{FileName: "main.bean", Line: 1})
+	assert.Equal(t, lspRange{Start: lspPosition{0, 0}, End: lspPosition{0, 22}}, r)
+}
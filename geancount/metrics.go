@@ -0,0 +1,84 @@
+package geancount
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// MetricsServer is a http.Handler exposing balances of a ledger file as OpenMetrics gauges.
+// The ledger is reloaded when the file or any of its includes changes
+type MetricsServer struct {
+	watcher *ledgerWatcher
+}
+
+// NewMetricsServer loads the ledger file and creates MetricsServer for it
+func NewMetricsServer(filename string) *MetricsServer {
+	return &MetricsServer{watcher: newLedgerWatcher(filename, nil)}
+}
+
+// Watch reloads the ledger when its files change until ctx is done
+func (s *MetricsServer) Watch(ctx context.Context, interval time.Duration) {
+	s.watcher.watch(ctx, interval)
+}
+
+// ServeHTTP writes metrics on /metrics
+func (s *MetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	writeMetrics(w, s.watcher.snapshot.Load())
+}
+
+func writeMetrics(w io.Writer, s *ledgerSnapshot) {
+	fmt.Fprintln(w, "# TYPE geancount_account_balance gauge")
+	fmt.Fprintln(w, "# HELP geancount_account_balance Balance of the account in the currency.")
+	accounts := make([]AccountName, 0, len(s.state.balances))
+	for a := range s.state.balances {
+		accounts = append(accounts, a)
+	}
+	slices.Sort(accounts)
+	for _, a := range accounts {
+		currencies := make([]Currency, 0, len(s.state.balances[a]))
+		for c := range s.state.balances[a] {
+			currencies = append(currencies, c)
+		}
+		slices.Sort(currencies)
+		for _, c := range currencies {
+			fmt.Fprintf(w, "geancount_account_balance{account=\"%s\",currency=\"%s\"} %s\n",
+				escapeLabel(string(a)), escapeLabel(string(c)), s.state.balances[a][c])
+		}
+	}
+
+	fmt.Fprintln(w, "# TYPE geancount_holding_market_value gauge")
+	fmt.Fprintln(w, "# HELP geancount_holding_market_value Market value of the held currency at the latest price.")
+	for _, h := range s.state.holdings() {
+		if value, ok := h.marketValue(); ok {
+			fmt.Fprintf(w, "geancount_holding_market_value{account=\"%s\",currency=\"%s\",price_currency=\"%s\"} %s\n",
+				escapeLabel(string(h.account)), escapeLabel(string(h.units.currency)), escapeLabel(string(value.currency)), value.value)
+		}
+	}
+
+	fmt.Fprintln(w, "# TYPE geancount_errors gauge")
+	fmt.Fprintln(w, "# HELP geancount_errors Number of errors found when the ledger was loaded.")
+	fmt.Fprintf(w, "geancount_errors %d\n", len(splitErrors(s.err)))
+
+	fmt.Fprintln(w, "# TYPE geancount_last_load_timestamp_seconds gauge")
+	fmt.Fprintln(w, "# HELP geancount_last_load_timestamp_seconds Time when the ledger was loaded.")
+	fmt.Fprintf(w, "geancount_last_load_timestamp_seconds %.3f\n", float64(s.loadedAt.UnixMilli())/1000)
+	fmt.Fprintln(w, "# EOF")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
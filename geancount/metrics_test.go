@@ -0,0 +1,32 @@
+package geancount
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsServer(t *testing.T) {
+	server := NewMetricsServer("testdata/prices.bean")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, openMetricsContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.True(t, strings.Contains(body, `geancount_account_balance{account="Assets:Bank",currency="EUR"} -102`+"\n"))
+	assert.True(t, strings.Contains(body, `geancount_holding_market_value{account="Assets:Invest",currency="S3",price_currency="EUR"} 60`+"\n"))
+	assert.True(t, strings.Contains(body, "geancount_errors 0\n"))
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\"b\\c\n`, escapeLabel("a\"b\\c\n"))
+}
@@ -0,0 +1,107 @@
+package geancount
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// openingBalancesAccount gets opening balances of migrated accounts
+const openingBalancesAccount AccountName = "Equity:Opening-Balances"
+
+// accountComponent converts a name of an account in other application to a component of an account name.
+// Characters other than letters, digits and dashes are replaced with dashes, the first letter is capitalized
+func accountComponent(name string) string {
+	b := strings.Builder{}
+	dash := false
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	component := b.String()
+	if component == "" {
+		return "Unnamed"
+	}
+	first := []rune(component)[0]
+	return string(unicode.ToUpper(first)) + component[len(string(first)):]
+}
+
+// migratedAccount makes an account name of the root like Assets and names of parent accounts and the account
+func migratedAccount(root string, names []string) AccountName {
+	if len(names) == 0 {
+		// The top level account itself can have postings too
+		names = []string{"Other"}
+	}
+	components := []string{root}
+	for _, name := range names {
+		components = append(components, accountComponent(name))
+	}
+	return AccountName(strings.Join(components, ":"))
+}
+
+// roundedQuotient divides x by y rounding the result to 8 decimal places without trailing zeros
+func roundedQuotient(x, y decimal.Decimal) decimal.Decimal {
+	return decimal.RequireFromString(x.DivRound(y, 8).String())
+}
+
+// migratedCurrency converts a symbol of a commodity to a currency, invalid characters are replaced with _
+func migratedCurrency(symbol string) Currency {
+	s := []byte(strings.ToUpper(strings.TrimSpace(symbol)))
+	for i, c := range s {
+		if !(c >= 'A' && c <= 'Z') && !isDigit(c) && c != '\'' && c != '.' && c != '_' && c != '-' {
+			s[i] = '_'
+		}
+	}
+	currency := string(s)
+	if currency == "" || !(currency[0] >= 'A' && currency[0] <= 'Z') {
+		currency = "C" + currency
+	}
+	// A currency ends with a letter or a digit
+	return Currency(strings.TrimRight(currency[:min(len(currency), 24)], "'._-"))
+}
+
+// openAccounts creates opening of every account with postings on the date of its first posting and
+// of other accounts with currencies on the date of the first directive
+func openAccounts(directives []Directive, currencies map[AccountName][]Currency) []Directive {
+	opened := map[AccountName]time.Time{}
+	var first time.Time
+	for _, d := range directives {
+		if first.IsZero() || d.Date().Before(first) {
+			first = d.Date()
+		}
+		t, ok := d.(Transaction)
+		if !ok {
+			continue
+		}
+		for _, p := range t.postings {
+			if date, ok := opened[p.account]; !ok || t.date.Before(date) {
+				opened[p.account] = t.date
+			}
+		}
+	}
+	for account := range currencies {
+		if _, ok := opened[account]; !ok {
+			opened[account] = first
+		}
+	}
+	accounts := make([]AccountName, 0, len(opened))
+	for account := range opened {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+	opens := make([]Directive, len(accounts))
+	for i, account := range accounts {
+		opens[i] = NewAccountOpen(opened[account], account, currencies[account])
+	}
+	return opens
+}
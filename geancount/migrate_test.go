@@ -0,0 +1,26 @@
+package geancount
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigratedNames(t *testing.T) {
+	assert.Equal(t, "Dining-out", accountComponent(" dining out "))
+	assert.Equal(t, "Café-bar", accountComponent("café & bar"))
+	assert.Equal(t, "Unnamed", accountComponent("&"))
+	assert.Equal(t, AccountName("Expenses:Food:Groceries"), migratedAccount("Expenses", []string{"Food", "groceries"}))
+	assert.Equal(t, AccountName("Expenses:Other"), migratedAccount("Expenses", nil))
+
+	assert.Equal(t, Currency("AAPL"), migratedCurrency("aapl"))
+	assert.Equal(t, Currency("VANGUARD_500"), migratedCurrency("Vanguard 500"))
+	assert.Equal(t, Currency("C123"), migratedCurrency("123"))
+	assert.Equal(t, Currency("BRK.B"), migratedCurrency("BRK.B."))
+}
+
+func TestRoundedQuotient(t *testing.T) {
+	assert.Equal(t, "0.33333333", roundedQuotient(decimal.NewFromInt(1), decimal.NewFromInt(3)).String())
+	assert.Equal(t, int32(-1), roundedQuotient(decimal.NewFromInt(3), decimal.NewFromInt(2)).Exponent())
+}
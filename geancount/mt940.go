@@ -0,0 +1,212 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// mt940Field is a field of MT940 statement like :61: with its continuation lines
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+var mt940FieldRe = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+
+// mt940Statements splits the source to statements, a statement is a list of fields ending with a line "-"
+func mt940Statements(src string) [][]mt940Field {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	statements := [][]mt940Field{}
+	fields := []mt940Field{}
+	for _, line := range strings.Split(src, "\n") {
+		if m := mt940FieldRe.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: line[len(m[0]):]})
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "-") {
+			if len(fields) > 0 {
+				statements = append(statements, fields)
+			}
+			fields = []mt940Field{}
+			continue
+		}
+		// Lines of SWIFT blocks like {1:...} before the first field are skipped
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	if len(fields) > 0 {
+		statements = append(statements, fields)
+	}
+	return statements
+}
+
+// mt940BalanceRe matches balance like C240131EUR987,50
+var mt940BalanceRe = regexp.MustCompile(`^([CD])([0-9]{6})([A-Z]{3})([0-9,]+)`)
+
+// mt940LineRe matches statement line like 2401020102DR12,50NTRFNONREF//BANKREF
+var mt940LineRe = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(R?[CD])([A-Z])?([0-9,]+)([NSF][A-Z0-9]{3})([^\n]*?)(?://([^\n]*))?(?:\n|$)`)
+
+func mt940Amount(s string) (decimal.Decimal, error) {
+	value, err := decimal.NewFromString(strings.Replace(s, ",", ".", 1))
+	if err != nil {
+		return value, fmt.Errorf("can not parse amount %q", s)
+	}
+	return value, nil
+}
+
+func mt940Date(s string) (time.Time, error) {
+	date, err := time.Parse("060102", s)
+	if err != nil {
+		return date, fmt.Errorf("can not parse date %q", s)
+	}
+	return date, nil
+}
+
+// ImportMT940 converts lines of SWIFT MT940 statements to transactions.
+// The closing balance is asserted on the day after the statement.
+// Lines which can not be converted are skipped and reported in the returned error
+func ImportMT940(r io.Reader, config BankConfig) ([]Directive, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	directives := []Directive{}
+	errs := []error{}
+	for _, fields := range mt940Statements(string(src)) {
+		var account AccountName = config.Account
+		var currency Currency
+		for i, f := range fields {
+			switch f.tag {
+			case "25":
+				account = config.account(strings.TrimSpace(f.value))
+			case "60F", "60M":
+				if m := mt940BalanceRe.FindStringSubmatch(f.value); m != nil {
+					currency = Currency(m[3])
+				}
+			case "61":
+				info := ""
+				if i+1 < len(fields) && fields[i+1].tag == "86" {
+					info = fields[i+1].value
+				}
+				t, err := mt940Transaction(config, account, currency, f.value, info)
+				if err != nil {
+					errs = append(errs, fmt.Errorf(":61:%s: %w", strings.SplitN(f.value, "\n", 2)[0], err))
+					continue
+				}
+				directives = append(directives, t)
+			case "62F":
+				b, err := mt940Balance(account, f.value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf(":62F:%s: %w", f.value, err))
+					continue
+				}
+				directives = append(directives, b)
+			}
+		}
+	}
+	slices.SortStableFunc(directives, compareDirectives)
+	return directives, errors.Join(errs...)
+}
+
+func mt940Balance(account AccountName, s string) (Balance, error) {
+	m := mt940BalanceRe.FindStringSubmatch(s)
+	if m == nil {
+		return Balance{}, errors.New("can not parse balance")
+	}
+	date, err := mt940Date(m[2])
+	if err != nil {
+		return Balance{}, err
+	}
+	value, err := mt940Amount(m[4])
+	if err != nil {
+		return Balance{}, err
+	}
+	if m[1] == "D" {
+		value = value.Neg()
+	}
+	return NewBalance(date.AddDate(0, 0, 1), account, NewAmount(value, Currency(m[3]))), nil
+}
+
+// mt940Transaction converts the statement line :61: with the information to account owner :86:.
+// The entry date is used if it is given, otherwise the value date
+func mt940Transaction(config BankConfig, account AccountName, currency Currency, line string, info string) (Transaction, error) {
+	m := mt940LineRe.FindStringSubmatch(line)
+	if m == nil {
+		return Transaction{}, errors.New("can not parse statement line")
+	}
+	date, err := mt940Date(m[1])
+	if err != nil {
+		return Transaction{}, err
+	}
+	if m[2] != "" {
+		entryDate, err := time.Parse("0102", m[2])
+		if err != nil {
+			return Transaction{}, fmt.Errorf("can not parse entry date %q", m[2])
+		}
+		// The entry date can be in the next or previous year than the value date
+		year := date.Year()
+		switch {
+		case date.Month() == time.December && entryDate.Month() == time.January:
+			year++
+		case date.Month() == time.January && entryDate.Month() == time.December:
+			year--
+		}
+		date = time.Date(year, entryDate.Month(), entryDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	value, err := mt940Amount(m[5])
+	if err != nil {
+		return Transaction{}, err
+	}
+	// Reversal of credit RC is a debit and reversal of debit RD is a credit
+	if m[3] == "D" || m[3] == "RC" {
+		value = value.Neg()
+	}
+	if currency == "" {
+		return Transaction{}, errors.New("statement has no opening balance :60F: or :60M: with the currency")
+	}
+	payee, narration := mt940Info(info)
+	posting := NewPosting(account, NewAmount(value, currency))
+	// The reference of the account owner comes first, the reference of the bank follows after //
+	if reference := strings.TrimSpace(m[7]); reference != "" && reference != "NONREF" {
+		posting = posting.WithMeta("customer_ref", reference)
+	}
+	if reference := strings.TrimSpace(m[8]); reference != "" {
+		posting = posting.WithMeta("bank_ref", reference)
+	}
+	postings := []Posting{posting, NewPosting(config.ContraAccount, Amount{})}
+	return NewTransaction(date, config.Flag, payee, narration, postings), nil
+}
+
+// mt940Info returns counterparty and remittance information of the field :86:. Structured field like
+// 166?00GUTSCHRIFT?20Invoice?32ACME has subfields ?20-?29 and ?60-?63 with remittance information
+// and ?32-?33 with the name, other fields are free text
+func mt940Info(s string) (string, string) {
+	s = strings.ReplaceAll(s, "\n", "")
+	if len(s) < 4 || !isDigit(s[0]) || !isDigit(s[1]) || !isDigit(s[2]) || s[3] != '?' {
+		return "", strings.Join(strings.Fields(s), " ")
+	}
+	names := []string{}
+	info := []string{}
+	for _, subfield := range strings.Split(s[4:], "?") {
+		if len(subfield) < 2 {
+			continue
+		}
+		code, text := subfield[:2], subfield[2:]
+		switch {
+		case code == "32" || code == "33":
+			names = append(names, text)
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			info = append(info, text)
+		}
+	}
+	// Subfields are parts of a text split at fixed width
+	return strings.TrimSpace(strings.Join(names, "")), strings.Join(strings.Fields(strings.Join(info, "")), " ")
+}
@@ -0,0 +1,62 @@
+package geancount
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportMT940(t *testing.T) {
+	config, err := ParseBankConfig([]byte("account: Assets:Unknown\ncontra_account: Expenses:Unknown\n" +
+		"accounts: {37040044/0532013000: Assets:Bank}\n"))
+	assert.Nil(t, err)
+	file, err := os.Open("testdata/statement.sta")
+	assert.Nil(t, err)
+	defer file.Close()
+	directives, err := ImportMT940(file, config)
+	assert.Equal(t, ":61:24AB05D1,00NCHG: can not parse statement line", err.Error())
+	assert.Equal(t, 5, len(directives))
+
+	salary := directives[0].(Transaction)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), salary.Date())
+	assert.Equal(t, "ACME GMBH", salary.Payee())
+	assert.Equal(t, "EREF+INV-1SVWZ+Salary January", salary.Narration())
+	assert.Equal(t, "2000.00 EUR", sourceAmount(salary.Postings()[0].Amount()))
+	assert.Equal(t, AccountName("Assets:Bank"), salary.Postings()[0].Account())
+	assert.Equal(t, map[string]string{"bank_ref": "BANKREF1"}, salary.Postings()[0].Meta())
+
+	debit := directives[1].(Transaction)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), debit.Date())
+	assert.Equal(t, "", debit.Payee())
+	assert.Equal(t, "Direct debit Power and Light", debit.Narration())
+	assert.Equal(t, "-50.00 EUR", sourceAmount(debit.Postings()[0].Amount()))
+	assert.Equal(t, map[string]string{"customer_ref": "KREF2"}, debit.Postings()[0].Meta())
+
+	fee := directives[2].(Transaction)
+	assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), fee.Date())
+	assert.Nil(t, fee.Postings()[0].Meta())
+	assert.Equal(t, "-10.00 EUR", sourceAmount(directives[3].(Transaction).Postings()[0].Amount()))
+
+	balance := directives[4].(Balance)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), balance.Date())
+	assert.Equal(t, "2939.00 EUR", sourceAmount(balance.Amount()))
+}
+
+func TestImportMT940WithoutOpeningBalance(t *testing.T) {
+	src := ":20:STARTUMSE\n:25:37040044/0532013000\n:61:2401020102CR2000,00NTRFNONREF//BANKREF1\n-}\n"
+	directives, err := ImportMT940(strings.NewReader(src), BankConfig{ImportConfig: ImportConfig{Account: "Assets:Bank"}})
+	assert.ErrorContains(t, err, "statement has no opening balance")
+	assert.Empty(t, directives)
+}
+
+func TestMT940Info(t *testing.T) {
+	payee, narration := mt940Info("166?00GUTSCHRIFT?20Invoice ?2112?32ACME?33 GMBH")
+	assert.Equal(t, "ACME GMBH", payee)
+	assert.Equal(t, "Invoice 12", narration)
+	payee, narration = mt940Info("Free  text")
+	assert.Equal(t, "", payee)
+	assert.Equal(t, "Free text", narration)
+}
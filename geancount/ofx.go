@@ -0,0 +1,462 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// OFXConfig describes accounts used for transactions of OFX statements
+type OFXConfig struct {
+	// Accounts of BankConfig are accounts of statements by their ACCTID
+	BankConfig `yaml:",inline"`
+	// HoldingsAccount is a parent of accounts of securities, like Assets:Broker for Assets:Broker:AAPL
+	HoldingsAccount AccountName `yaml:"holdings_account"`
+	// GainsAccount gets gains and losses of sales
+	GainsAccount AccountName `yaml:"gains_account"`
+	// FeesAccount gets commissions and fees of trades
+	FeesAccount AccountName `yaml:"fees_account"`
+	// Securities are commodities by UNIQUEID of securities, tickers from the statement are used for others
+	Securities map[string]Currency `yaml:"securities"`
+}
+
+// LoadOFXConfig reads the config from YAML file
+func LoadOFXConfig(filename string) (OFXConfig, error) {
+	return loadImportConfig(filename, ParseOFXConfig)
+}
+
+// ParseOFXConfig parses YAML config, fills defaults and checks it
+func ParseOFXConfig(data []byte) (OFXConfig, error) {
+	config := OFXConfig{GainsAccount: "Income:Gains", FeesAccount: "Expenses:Fees"}
+	config.ImportConfig = defaultImportConfig
+	if err := parseImportConfig(data, &config, &config.ImportConfig); err != nil {
+		return config, err
+	}
+	if err := config.validateAccounts(); err != nil {
+		return config, err
+	}
+	for _, account := range []AccountName{config.GainsAccount, config.FeesAccount} {
+		if !isAccount(string(account)) {
+			return config, fmt.Errorf("%q is not a valid account", account)
+		}
+	}
+	if config.HoldingsAccount != "" && !isAccount(string(config.HoldingsAccount)) {
+		return config, fmt.Errorf("holdings_account %q is not a valid account", config.HoldingsAccount)
+	}
+	return config, nil
+}
+
+// ofxElement is an element of OFX document. Values are kept only for elements without children
+type ofxElement struct {
+	name     string
+	value    string
+	children []*ofxElement
+}
+
+// child returns the first child found by path of names
+func (e *ofxElement) child(path ...string) *ofxElement {
+	for _, name := range path {
+		if e == nil {
+			return nil
+		}
+		i := slices.IndexFunc(e.children, func(c *ofxElement) bool { return c.name == name })
+		if i == -1 {
+			return nil
+		}
+		e = e.children[i]
+	}
+	return e
+}
+
+// text returns value of the child found by path, empty if there is no such child
+func (e *ofxElement) text(path ...string) string {
+	if c := e.child(path...); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+// descendants returns all elements with the name in order of the document
+func (e *ofxElement) descendants(name string) []*ofxElement {
+	found := []*ofxElement{}
+	for _, c := range e.children {
+		if c.name == name {
+			found = append(found, c)
+		}
+		found = append(found, c.descendants(name)...)
+	}
+	return found
+}
+
+// parseOFX parses OFX 1.x SGML and OFX 2.x XML. The header is skipped, elements with values
+// may be not closed like in SGML, they are closed by the next tag
+func parseOFX(data []byte) (*ofxElement, error) {
+	if !utf8.Valid(data) {
+		data = decodeWindows1252(data)
+	}
+	s := string(data)
+	start := strings.Index(s, "<OFX>")
+	if start == -1 {
+		return nil, errors.New("OFX element is not found")
+	}
+	root := &ofxElement{}
+	stack := []*ofxElement{root}
+	for i := start; i < len(s); {
+		top := stack[len(stack)-1]
+		if s[i] != '<' {
+			end := strings.IndexByte(s[i:], '<')
+			if end == -1 {
+				end = len(s) - i
+			}
+			if text := strings.TrimSpace(s[i : i+end]); text != "" && len(top.children) == 0 {
+				top.value = html.UnescapeString(text)
+			}
+			i += end
+			continue
+		}
+		end := strings.IndexByte(s[i:], '>')
+		if end == -1 {
+			return nil, errors.New("tag is not closed")
+		}
+		tag := strings.TrimSpace(s[i+1 : i+end])
+		i += end + 1
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+		if name, ok := strings.CutPrefix(tag, "/"); ok {
+			// Unclosed elements are closed by the closing tag of their parent. They are leaves like an empty
+			// <MEMO>, so elements opened after them are their next siblings rather than their children
+			name = strings.ToUpper(strings.TrimSpace(name))
+			for k := len(stack) - 1; k > 0; k-- {
+				if stack[k].name == name {
+					for j := len(stack) - 1; j > k; j-- {
+						leaf, parent := stack[j], stack[j-1]
+						parent.children = append(parent.children, leaf.children...)
+						leaf.children = nil
+					}
+					stack = stack[:k]
+					break
+				}
+			}
+			continue
+		}
+		if top.value != "" && len(stack) > 1 {
+			stack = stack[:len(stack)-1]
+			top = stack[len(stack)-1]
+		}
+		selfClosing := strings.HasSuffix(tag, "/")
+		fields := strings.Fields(strings.TrimSuffix(tag, "/"))
+		if len(fields) == 0 {
+			continue
+		}
+		e := &ofxElement{name: strings.ToUpper(fields[0])}
+		top.children = append(top.children, e)
+		if !selfClosing {
+			stack = append(stack, e)
+		}
+	}
+	return root.child("OFX"), nil
+}
+
+// windows1252 are runes of bytes 0x80-0x9F in Windows-1252, other bytes are the same as in Latin-1
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 converts text in Windows-1252 used by OFX 1.x to UTF-8
+func decodeWindows1252(data []byte) []byte {
+	b := strings.Builder{}
+	for _, c := range data {
+		if c >= 0x80 && c < 0xA0 {
+			b.WriteRune(windows1252[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return []byte(b.String())
+}
+
+func ofxDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("can not parse date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return date, fmt.Errorf("can not parse date %q", s)
+	}
+	return date, nil
+}
+
+// ofxNumber parses amount, missing amount is zero
+func ofxNumber(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	if !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return value, fmt.Errorf("can not parse amount %q", s)
+	}
+	return value, nil
+}
+
+// ImportOFX converts transactions of bank, credit card and investment statements to directives.
+// Ending balances of statements are asserted on the day after they are reported.
+// Transactions which can not be converted are skipped and reported in the returned error.
+// Sold units are reduced at the average cost of lots in holdings and of units bought earlier in the statements,
+// holdings can be nil if the ledger is not known
+func ImportOFX(r io.Reader, config OFXConfig, holdings *LedgerState) ([]Directive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	ofx, err := parseOFX(data)
+	if err != nil {
+		return nil, err
+	}
+	securities := map[string]Currency{}
+	for _, info := range ofx.descendants("SECINFO") {
+		if ticker := info.text("TICKER"); ticker != "" {
+			securities[info.text("SECID", "UNIQUEID")] = Currency(strings.ToUpper(ticker))
+		}
+	}
+	for id, commodity := range config.Securities {
+		securities[id] = commodity
+	}
+	imp := ofxImport{config: config, securities: securities, holdings: holdings, lots: map[AccountName][]Lot{}}
+	for _, name := range []string{"STMTRS", "CCSTMTRS"} {
+		for _, stmt := range ofx.descendants(name) {
+			imp.statement(stmt)
+		}
+	}
+	for _, stmt := range ofx.descendants("INVSTMTRS") {
+		imp.investmentStatement(stmt)
+	}
+	slices.SortStableFunc(imp.directives, compareDirectives)
+	return imp.directives, errors.Join(imp.errs...)
+}
+
+// ofxImport collects directives and errors of statements
+type ofxImport struct {
+	config     OFXConfig
+	securities map[string]Currency
+	holdings   *LedgerState
+	// lots are lots of holdings accounts changed by imported trades
+	lots       map[AccountName][]Lot
+	directives []Directive
+	errs       []error
+}
+
+func (imp *ofxImport) add(d Directive, err error, fitid string) {
+	if err != nil {
+		if fitid != "" {
+			err = fmt.Errorf("transaction %s: %w", fitid, err)
+		}
+		imp.errs = append(imp.errs, err)
+		return
+	}
+	imp.directives = append(imp.directives, d)
+}
+
+// statement imports bank or credit card statement
+func (imp *ofxImport) statement(stmt *ofxElement) {
+	id := stmt.text("BANKACCTFROM", "ACCTID")
+	if id == "" {
+		id = stmt.text("CCACCTFROM", "ACCTID")
+	}
+	account := imp.config.account(id)
+	currency := Currency(stmt.text("CURDEF"))
+	for _, trn := range stmt.child("BANKTRANLIST").descendants("STMTTRN") {
+		t, err := imp.bankTransaction(trn, account, currency)
+		imp.add(t, err, trn.text("FITID"))
+	}
+	if balance := stmt.child("LEDGERBAL"); balance != nil {
+		b, err := imp.balance(account, balance.text("BALAMT"), balance.text("DTASOF"), currency)
+		imp.add(b, err, "")
+	}
+}
+
+// balance creates assertion of the balance at the end of the day
+func (imp *ofxImport) balance(account AccountName, amount string, date string, currency Currency) (Directive, error) {
+	asOf, err := ofxDate(date)
+	if err != nil {
+		return nil, fmt.Errorf("balance: %w", err)
+	}
+	value, err := ofxNumber(amount)
+	if err != nil {
+		return nil, fmt.Errorf("balance: %w", err)
+	}
+	return NewBalance(asOf.AddDate(0, 0, 1), account, NewAmount(value, currency)), nil
+}
+
+func (imp *ofxImport) bankTransaction(trn *ofxElement, account AccountName, currency Currency) (Directive, error) {
+	date, err := ofxDate(trn.text("DTPOSTED"))
+	if err != nil {
+		return nil, err
+	}
+	if trn.text("TRNAMT") == "" {
+		return nil, errors.New("amount is not set")
+	}
+	value, err := ofxNumber(trn.text("TRNAMT"))
+	if err != nil {
+		return nil, err
+	}
+	if symbol := trn.text("CURRENCY", "CURSYM"); symbol != "" {
+		currency = Currency(symbol)
+	}
+	payee := trn.text("NAME")
+	if payee == "" {
+		payee = trn.text("PAYEE", "NAME")
+	}
+	narration := trn.text("MEMO")
+	if narration == "" {
+		payee, narration = "", payee
+	}
+	posting := NewPosting(account, NewAmount(value, currency))
+	if fitid := trn.text("FITID"); fitid != "" {
+		posting = posting.WithMeta("fitid", fitid)
+	}
+	postings := []Posting{posting, NewPosting(imp.config.ContraAccount, Amount{})}
+	return NewTransaction(date, imp.config.Flag, payee, narration, postings), nil
+}
+
+// investmentStatement imports trades, income and cash transactions of a brokerage account
+func (imp *ofxImport) investmentStatement(stmt *ofxElement) {
+	account := imp.config.account(stmt.text("INVACCTFROM", "ACCTID"))
+	currency := Currency(stmt.text("CURDEF"))
+	for _, e := range stmt.child("INVTRANLIST").children {
+		fitid := e.child("INVTRAN").text("FITID")
+		switch {
+		case e.name == "INVBANKTRAN":
+			t, err := imp.bankTransaction(e.child("STMTTRN"), account, currency)
+			imp.add(t, err, e.text("STMTTRN", "FITID"))
+		case e.child("INVBUY") != nil:
+			t, err := imp.trade(e.child("INVBUY"), account, currency, false)
+			imp.add(t, err, e.text("INVBUY", "INVTRAN", "FITID"))
+		case e.child("INVSELL") != nil:
+			t, err := imp.trade(e.child("INVSELL"), account, currency, true)
+			imp.add(t, err, e.text("INVSELL", "INVTRAN", "FITID"))
+		case e.name == "INCOME":
+			t, err := imp.income(e, account, currency)
+			imp.add(t, err, fitid)
+		case e.name == "DTSTART" || e.name == "DTEND":
+		default:
+			imp.add(nil, fmt.Errorf("%s is not supported", e.name), fitid)
+		}
+	}
+	if cash := stmt.text("INVBAL", "AVAILCASH"); cash != "" {
+		b, err := imp.balance(account, cash, stmt.text("DTASOF"), currency)
+		imp.add(b, err, "")
+	}
+}
+
+func (imp *ofxImport) security(id string) (Currency, error) {
+	commodity, ok := imp.securities[id]
+	if !ok || !isCurrency(string(commodity)) {
+		return "", fmt.Errorf("ticker of security %s is unknown", id)
+	}
+	return commodity, nil
+}
+
+// heldLots returns lots of the commodity in the holdings account
+func (imp *ofxImport) heldLots(account AccountName, commodity Currency) []Lot {
+	if lots, ok := imp.lots[account]; ok {
+		return lots
+	}
+	lots := []Lot{}
+	if imp.holdings != nil {
+		lots = slices.Clone(imp.holdings.inventories[account][commodity])
+	}
+	imp.lots[account] = lots
+	return lots
+}
+
+// trade creates transaction of buying or selling securities. Bought units are held at cost,
+// sold units reduce lots at their average cost or at the price of the sale if no lots are known
+// and the gain is balanced by a blank posting
+func (imp *ofxImport) trade(inv *ofxElement, account AccountName, currency Currency, sell bool) (Directive, error) {
+	if imp.config.HoldingsAccount == "" {
+		return nil, errors.New("holdings_account is not set")
+	}
+	tran := inv.child("INVTRAN")
+	date, err := ofxDate(tran.text("DTTRADE"))
+	if err != nil {
+		return nil, err
+	}
+	commodity, err := imp.security(inv.text("SECID", "UNIQUEID"))
+	if err != nil {
+		return nil, err
+	}
+	numbers := map[string]decimal.Decimal{}
+	for _, name := range []string{"UNITS", "UNITPRICE", "TOTAL", "COMMISSION", "FEES", "TAXES", "LOAD"} {
+		if numbers[name], err = ofxNumber(inv.text(name)); err != nil {
+			return nil, err
+		}
+	}
+	if symbol := inv.text("CURRENCY", "CURSYM"); symbol != "" {
+		currency = Currency(symbol)
+	}
+	holdings := AccountName(fmt.Sprintf("%s:%s", imp.config.HoldingsAccount, commodity))
+	units := numbers["UNITS"].Abs()
+	cost := NewAmount(numbers["UNITPRICE"], currency)
+	lots := imp.heldLots(holdings, commodity)
+	if sell {
+		held, value := decimal.Zero, decimal.Zero
+		for _, lot := range lots {
+			held = held.Add(lot.amount.value)
+			value = value.Add(lot.amount.value.Mul(lot.cost.value))
+		}
+		if held.IsPositive() {
+			cost = NewAmount(roundedQuotient(value, held), lots[0].cost.currency)
+		}
+		units = units.Neg()
+	}
+	imp.lots[holdings] = append(lots, Lot{amount: NewAmount(units, commodity), cost: cost, date: date})
+	holding := NewPostingAtCost(holdings, NewAmount(units, commodity), &cost)
+	cash := NewPosting(account, NewAmount(numbers["TOTAL"], currency))
+	if fitid := tran.text("FITID"); fitid != "" {
+		cash = cash.WithMeta("fitid", fitid)
+	}
+	postings := []Posting{holding, cash}
+	fees := numbers["COMMISSION"].Add(numbers["FEES"]).Add(numbers["TAXES"]).Add(numbers["LOAD"])
+	if !fees.IsZero() {
+		postings = append(postings, NewPosting(imp.config.FeesAccount, NewAmount(fees, currency)))
+	}
+	if sell {
+		postings = append(postings, NewPosting(imp.config.GainsAccount, Amount{}))
+	}
+	return NewTransaction(date, imp.config.Flag, "", tran.text("MEMO"), postings), nil
+}
+
+// income creates transaction of dividends or interest paid in cash
+func (imp *ofxImport) income(e *ofxElement, account AccountName, currency Currency) (Directive, error) {
+	tran := e.child("INVTRAN")
+	date, err := ofxDate(tran.text("DTTRADE"))
+	if err != nil {
+		return nil, err
+	}
+	total, err := ofxNumber(e.text("TOTAL"))
+	if err != nil {
+		return nil, err
+	}
+	narration := tran.text("MEMO")
+	if narration == "" {
+		narration = strings.TrimSpace(e.text("INCOMETYPE") + " " + string(imp.securities[e.text("SECID", "UNIQUEID")]))
+	}
+	cash := NewPosting(account, NewAmount(total, currency))
+	if fitid := tran.text("FITID"); fitid != "" {
+		cash = cash.WithMeta("fitid", fitid)
+	}
+	postings := []Posting{cash, NewPosting(imp.config.ContraAccount, Amount{})}
+	return NewTransaction(date, imp.config.Flag, "", narration, postings), nil
+}
@@ -0,0 +1,157 @@
+package geancount
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOFX(t *testing.T) {
+	ofx, err := parseOFX([]byte("OFXHEADER:100\n<OFX><A><B>1<C>x &lt; y</A><D/><E>2</E></OFX>"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ofx.children))
+	assert.Equal(t, "1", ofx.text("A", "B"))
+	assert.Equal(t, "x < y", ofx.text("A", "C"))
+	assert.Equal(t, "", ofx.text("D"))
+	assert.Equal(t, "2", ofx.text("E"))
+	assert.Nil(t, ofx.child("A", "X", "Y"))
+
+	ofx, err = parseOFX([]byte("<OFX><STMTTRN><MEMO><TRNAMT>-5<NAME>Shop<TRN><FITID>1</TRN></STMTTRN></OFX>"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", ofx.text("STMTTRN", "MEMO"))
+	assert.Equal(t, "-5", ofx.text("STMTTRN", "TRNAMT"))
+	assert.Equal(t, "Shop", ofx.text("STMTTRN", "NAME"))
+	assert.Equal(t, "1", ofx.text("STMTTRN", "TRN", "FITID"))
+	assert.Equal(t, 4, len(ofx.child("STMTTRN").children))
+
+	_, err = parseOFX([]byte("<HTML></HTML>"))
+	assert.NotNil(t, err)
+}
+
+func TestImportOFXBankStatement(t *testing.T) {
+	config, err := ParseOFXConfig([]byte("account: Assets:Bank\ncontra_account: Expenses:Unknown\n" +
+		"accounts: {\"4111\": Liabilities:Card}\n"))
+	assert.Nil(t, err)
+	file, err := os.Open("testdata/bank.ofx")
+	assert.Nil(t, err)
+	defer file.Close()
+	directives, err := ImportOFX(file, config, nil)
+	assert.Equal(t, "transaction broken: can not parse date \"2024\"", err.Error())
+	assert.Equal(t, 5, len(directives))
+
+	salary := directives[0].(Transaction)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), salary.Date())
+	assert.Equal(t, "ACME Corp", salary.Payee())
+	assert.Equal(t, "Salary", salary.Narration())
+	assert.Equal(t, map[string]string{"fitid": "2024010201"}, salary.Postings()[0].Meta())
+
+	coffee := directives[1].(Transaction)
+	assert.Equal(t, "Café & Bar", coffee.Payee())
+	assert.Equal(t, "Coffee – beans", coffee.Narration())
+	assert.Equal(t, "-42.50 USD", sourceAmount(coffee.Postings()[0].Amount()))
+
+	card := directives[2].(Transaction)
+	assert.Equal(t, "", card.Payee())
+	assert.Equal(t, "Books", card.Narration())
+	assert.Equal(t, AccountName("Liabilities:Card"), card.Postings()[0].Account())
+
+	balance := directives[3].(Balance)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), balance.Date())
+	assert.Equal(t, AccountName("Assets:Bank"), balance.Account())
+	assert.Equal(t, "1457.50 USD", sourceAmount(balance.Amount()))
+	assert.Equal(t, AccountName("Liabilities:Card"), directives[4].(Balance).Account())
+
+	ls := loadImported(t, directives, "Assets:Bank", "Liabilities:Card", "Expenses:Unknown")
+	assert.Equal(t, "1457.50", ls.Balance("Assets:Bank")["USD"].StringFixed(2))
+}
+
+func TestImportOFXInvestmentStatement(t *testing.T) {
+	config, err := ParseOFXConfig([]byte("account: Assets:Broker:Cash\ncontra_account: Income:Dividends\n" +
+		"holdings_account: Assets:Broker\n"))
+	assert.Nil(t, err)
+	file, err := os.Open("testdata/broker.ofx")
+	assert.Nil(t, err)
+	defer file.Close()
+	directives, err := ImportOFX(file, config, nil)
+	assert.Equal(t, "transaction reinv1: REINVEST is not supported", err.Error())
+	assert.Equal(t, 5, len(directives))
+
+	buy := directives[1].(Transaction)
+	assert.Equal(t, "Buy Apple", buy.Narration())
+	holding := buy.Postings()[0]
+	assert.Equal(t, AccountName("Assets:Broker:AAPL"), holding.Account())
+	assert.True(t, holding.AtCost())
+	assert.Equal(t, "150.00 USD", sourceAmount(*holding.Price()))
+	assert.Equal(t, map[string]string{"fitid": "buy1"}, buy.Postings()[1].Meta())
+	assert.Equal(t, "4.95 USD", sourceAmount(buy.Postings()[2].Amount()))
+
+	assert.Equal(t, "DIV AAPL", directives[2].(Transaction).Narration())
+
+	sell := directives[3].(Transaction)
+	assert.Equal(t, "-10 AAPL", sourceAmount(sell.Postings()[0].Amount()))
+	assert.Equal(t, "150 USD", sourceAmount(*sell.Postings()[0].Price()))
+	assert.Equal(t, AccountName("Income:Gains"), sell.Postings()[3].Account())
+
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives[3:4], DefaultFormatOptions))
+	assert.Equal(t, "2024-01-20 * \"Sell Apple\"\n"+
+		"  Assets:Broker:AAPL      -10 AAPL {150 USD}\n"+
+		"  Assets:Broker:Cash  1595.05 USD\n"+
+		"    fitid: \"sell1\"\n"+
+		"  Expenses:Fees          4.95 USD\n"+
+		"  Income:Gains\n", out.String())
+
+	ls := loadImported(t, directives, "Assets:Broker:Cash", "Assets:Broker:AAPL", "Income:Dividends", "Expenses:Fees", "Income:Gains")
+	assert.Equal(t, "2092.50", ls.Balance("Assets:Broker:Cash")["USD"].StringFixed(2))
+	assert.Equal(t, "-100.00", ls.Balance("Income:Gains")["USD"].StringFixed(2))
+}
+
+// loadImported writes directives with opening of accounts and loads them back
+func TestImportOFXPartialSell(t *testing.T) {
+	src := "2000-01-01 open Assets:Broker:Cash\n2000-01-01 open Assets:Broker:AAPL\n2000-01-01 open Equity:Opening\n" +
+		"2000-01-01 open Expenses:Fees\n2000-01-01 open Income:Gains\n\n" +
+		"2023-06-01 * \"Buy\"\n  Assets:Broker:AAPL  10 AAPL {100 USD}\n  Equity:Opening\n\n" +
+		"2023-07-01 * \"Buy\"\n  Assets:Broker:AAPL  10 AAPL {200 USD}\n  Equity:Opening\n"
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(src)))
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+
+	config, err := ParseOFXConfig([]byte("account: Assets:Broker:Cash\ncontra_account: Income:Dividends\nholdings_account: Assets:Broker\n"))
+	assert.Nil(t, err)
+	ofx := "<OFX><INVSTMTRS><CURDEF>USD<INVACCTFROM><ACCTID>B-1</INVACCTFROM><INVTRANLIST>" +
+		"<SELLSTOCK><INVSELL><INVTRAN><FITID>sell1<DTTRADE>20240120</INVTRAN>" +
+		"<SECID><UNIQUEID>AAPL</SECID><UNITS>-5<UNITPRICE>160.00<COMMISSION>4.95<TOTAL>795.05</INVSELL></SELLSTOCK>" +
+		"</INVTRANLIST></INVSTMTRS></OFX>"
+	config.Securities = map[string]Currency{"AAPL": "AAPL"}
+	directives, err := ImportOFX(strings.NewReader(ofx), config, &ls)
+	assert.Nil(t, err)
+	assert.Len(t, directives, 1)
+
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives, DefaultFormatOptions))
+	ledger = NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(src+"\n"+out.String())))
+	ls, err = ledger.GetState()
+	assert.Nil(t, err, out.String())
+	assert.Equal(t, "15", ls.Balance("Assets:Broker:AAPL")["AAPL"].String())
+	assert.Equal(t, "-50.00", ls.Balance("Income:Gains")["USD"].StringFixed(2))
+}
+
+func loadImported(t *testing.T, directives []Directive, accounts ...AccountName) LedgerState {
+	opens := []Directive{}
+	for _, account := range accounts {
+		opens = append(opens, NewAccountOpen(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), account, nil))
+	}
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, append(opens, directives...), DefaultFormatOptions))
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("imported.bean", strings.NewReader(out.String())))
+	ls, err := ledger.GetState()
+	assert.Nil(t, err, out.String())
+	return ls
+}
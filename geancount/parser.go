@@ -1,21 +1,21 @@
 package geancount
 
 import (
-	"bufio"
 	"cmp"
 	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
 	"slices"
 	"strings"
 	"unicode/utf8"
 )
 
-// Token is a minimal part of input
+// Token is a minimal part of input, text of strings is without quotes
 type Token struct {
-	text     string
-	isQuoted bool
+	text      string
+	column    int32
+	endColumn int32
+	kind      SyntaxKind
 }
 
 // Line is collection of tokens
@@ -39,131 +39,66 @@ func (l Line) String() string {
 	}
 	for _, t := range l.tokens {
 		sb.WriteString(" | ")
-		if t.isQuoted {
+		if t.kind == SyntaxString {
 			sb.WriteRune('"')
 		}
 		sb.WriteString(t.text)
-		if t.isQuoted {
+		if t.kind == SyntaxString {
 			sb.WriteRune('"')
 		}
 	}
 	return sb.String()
 }
 
-// parseState is a helper to store current state of the parser
-type parserState struct {
-	// token
-	sb       strings.Builder
-	inQuote  bool
-	isQuoted bool
-	prev     rune
-
-	// line
-	cursorLineNum int // where cursor is
-	lineNum       int // where current line is started
-	inComment     bool
-	tokens        []Token
-	isIndented    bool
+// parseInput reads the source and converts its syntax tree into lines without trivia
+func parseInput(r io.Reader) ([]Line, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return syntaxTreeLines(ParseSyntaxTree(src)), nil
 }
 
-func (p *parserState) addToken() {
-	if p.sb.Len() > 0 {
-		if !p.inComment {
-			token := Token{
-				text:     p.sb.String(),
-				isQuoted: p.isQuoted,
+func syntaxTreeLines(tree *SyntaxTree) []Line {
+	syntaxLines := tree.lines()
+	n := 0
+	for _, sl := range syntaxLines {
+		for _, t := range sl.tokens {
+			if !t.IsTrivia() {
+				n++
 			}
-			p.tokens = append(p.tokens, token)
 		}
-		p.sb.Reset()
 	}
-	p.inQuote = false
-	p.isQuoted = false
-}
-
-func (p *parserState) createLine() Line {
-	line := Line{lineNum: p.lineNum, tokens: p.tokens, isIndented: p.isIndented}
-	p.tokens = []Token{}
-	p.isIndented = false
-	p.inComment = false
-	return line
-}
-
-func parseInput(r io.Reader) ([]Line, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanRunes)
-	lines := []Line{}
-	state := parserState{lineNum: 1, cursorLineNum: 1}
-	for scanner.Scan() {
-		r, _ := utf8.DecodeRune(scanner.Bytes())
-		switch r {
-		case '\n':
-			state.cursorLineNum++
-			if state.inQuote {
-				state.sb.WriteRune(r)
-			} else {
-				state.addToken()
-				lines = append(lines, state.createLine())
-				state.lineNum = state.cursorLineNum
-			}
-		case ' ', '\t':
-			if state.inQuote {
-				state.sb.WriteRune(r)
-				break
-			} else {
-				if len(state.tokens) == 0 && state.sb.Len() == 0 && !state.inComment {
-					state.isIndented = true
-				} else {
-					state.addToken()
-				}
-			}
-		case '"':
-			// TODO: Deal with unbalanced quotes
-			if state.inQuote {
-				if state.prev == '\\' {
-					s := state.sb.String()
-					// Remove previos \ from the string
-					state.sb.Reset()
-					state.sb.WriteString(s[:len(s)-1])
-					state.sb.WriteRune(r)
-				} else {
-					state.isQuoted = true
-					state.addToken()
-				}
-			} else {
-				state.inQuote = true
-			}
-		case '{', '}':
-			state.addToken()
-			state.sb.WriteRune(r)
-			if !state.inComment {
-				state.addToken()
-			}
-		case ';':
-			if !state.inComment {
-				state.addToken()
-				state.inComment = true
-			} else {
-				state.sb.WriteRune(r)
+	// Tokens of all lines share one array
+	tokens := make([]Token, 0, n)
+	lines := make([]Line, 0, len(syntaxLines)+1)
+	nextLineNum := 1
+	for _, sl := range syntaxLines {
+		start := len(tokens)
+		for _, t := range sl.tokens {
+			if t.IsTrivia() {
+				continue
 			}
-		default:
-			state.sb.WriteRune(r)
+			tokens = append(tokens, Token{
+				text:      t.Value(),
+				kind:      t.kind,
+				column:    t.column,
+				endColumn: t.column + int32(utf8.RuneCountInString(t.text)),
+			})
 		}
-		state.prev = r
-	}
-	// if there is not EOL in the end add last line
-	if state.sb.Len() > 0 {
-		state.addToken()
-	}
-	if len(state.tokens) > 0 {
-		lines = append(lines, state.createLine())
-		state.lineNum = state.cursorLineNum + 1
+		line := Line{lineNum: sl.LineNum(), isIndented: sl.IsIndented(), tokens: tokens[start:len(tokens):len(tokens)]}
+		// The last line without newline is skipped if it has nothing but trivia
+		if !sl.endsWithNewline() && line.IsBlank() {
+			continue
+		}
+		lines = append(lines, line)
+		nextLineNum = sl.lastLineNum() + 1
 	}
 	// Ensure the last line is blank
 	if len(lines) > 0 && !lines[len(lines)-1].IsBlank() {
-		lines = append(lines, Line{lineNum: state.lineNum})
+		lines = append(lines, Line{lineNum: nextLineNum})
 	}
-	return lines, nil
+	return lines
 }
 
 // LineGroup is collection of lines that form one directive
@@ -196,17 +131,32 @@ func groupLines(lines []Line) ([]LineGroup, error) {
 	return lineGroups, nil
 }
 
+// sortDirectives sorts directives by date and order, directives of the same date and order stay in
+// the order they were loaded
 func (l *Ledger) sortDirectives() {
-	slices.SortFunc(l.directives, func(i, j Directive) int {
-		if i.Date().Before(j.Date()) {
-			return -1
-		} else if i.Date().After(j.Date()) {
-			return 1
-		}
-		return cmp.Compare(i.Order(), j.Order())
-	})
+	slices.SortStableFunc(l.directives, compareDirectives)
+}
+
+// compareDirectives compares directives by date and order
+func compareDirectives(i, j Directive) int {
+	if c := i.Date().Compare(j.Date()); c != 0 {
+		return c
+	}
+	return cmp.Compare(i.Order(), j.Order())
 }
-func (l *Ledger) createDirectives(lineGroups []LineGroup, fileName string, parentDir string) error {
+
+// parsedFile is a result of parsing one file, includes are loaded separately so it can be cached
+type parsedFile struct {
+	directives          []Directive
+	includes            []LineGroup
+	operatingCurrencies []Currency
+	// documents are lines of documents options
+	documents []Line
+	err       error
+}
+
+func createDirectives(lineGroups []LineGroup, fileName string) *parsedFile {
+	parsed := &parsedFile{}
 	directives := []Directive{}
 	errs := []error{}
 	for _, lg := range lineGroups {
@@ -214,16 +164,13 @@ func (l *Ledger) createDirectives(lineGroups []LineGroup, fileName string, paren
 		case "pushtag", "poptag": // TODO implement
 			continue
 		case "include":
-			err := l.include(lg, parentDir)
-			if err != nil {
-				errs = append(errs, err)
-			}
+			parsed.includes = append(parsed.includes, lg)
 		case "option":
-			err := l.applyOption(lg)
+			err := parsed.applyOption(lg)
 			if err == ErrNotDirective {
 				continue
 			} else if err != nil {
-				errs = append(errs, err)
+				errs = append(errs, locateError(err, CodeParse, fileName, lg.lines[0].lineNum))
 			}
 		default:
 			var err error
@@ -239,6 +186,8 @@ func (l *Ledger) createDirectives(lineGroups []LineGroup, fileName string, paren
 				directive, err = newPad(lg, fileName)
 			case "price":
 				directive, err = newPrice(lg, fileName)
+			case "document":
+				directive, err = newDocument(lg, fileName)
 			case "*", "!", "txn", "p":
 				directive, err = newTransaction(lg, fileName)
 				if err == nil {
@@ -257,32 +206,17 @@ func (l *Ledger) createDirectives(lineGroups []LineGroup, fileName string, paren
 			if err == ErrNotDirective { // just ignore
 				continue
 			} else if err != nil {
-				errs = append(errs, err)
+				errs = append(errs, locateError(err, CodeParse, fileName, lg.lines[0].lineNum))
 			}
 			directives = append(directives, directive)
 		}
 	}
-	l.directives = append(l.directives, directives...)
-	return errors.Join(errs...)
+	parsed.directives = directives
+	parsed.err = errors.Join(errs...)
+	return parsed
 }
 
-func (l *Ledger) include(lg LineGroup, parentDir string) error {
-	line := lg.lines[0]
-	if len(line.tokens) < 2 {
-		return ErrNotDirective
-	}
-	includeFilename := line.tokens[1].text
-	absIncludeFilename, err := filepath.Abs(includeFilename)
-	if err != nil {
-		return err
-	}
-	if absIncludeFilename != includeFilename {
-		includeFilename = filepath.Join(parentDir, includeFilename)
-	}
-	err = l.loadFile(includeFilename, false)
-	return err
-}
-func (l *Ledger) applyOption(lg LineGroup) error {
+func (p *parsedFile) applyOption(lg LineGroup) error {
 	line := lg.lines[0]
 	if len(line.tokens) < 2 {
 		return ErrNotDirective
@@ -292,9 +226,16 @@ func (l *Ledger) applyOption(lg LineGroup) error {
 		if len(line.tokens) < 3 {
 			return fmt.Errorf("operating_currency has no currency")
 		}
-		l.operatingCurrencies = append(l.operatingCurrencies, Currency(line.tokens[2].text))
+		p.operatingCurrencies = append(p.operatingCurrencies, Currency(line.tokens[2].text))
+	case "documents":
+		if len(line.tokens) < 3 {
+			return fmt.Errorf("documents has no directory")
+		}
+		p.documents = append(p.documents, line)
 	default:
-		return fmt.Errorf("Unknown option %s", optionName)
+		d := newDiagnostic(CodeUnknownOption, "Unknown option %s", optionName).at(tokenSpan(line, 1))
+		d.Severity = SeverityWarning
+		return d
 	}
 	return nil
 }
@@ -20,30 +20,30 @@ func TestGetLine(t *testing.T) {
 	assert.Nil(t, err)
 	expected := []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-01", isQuoted: false},
-			{text: "open", isQuoted: false},
-			{text: "Equity:Opening-Balances", isQuoted: false},
+			{text: "2000-01-01", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "open", kind: SyntaxWord, column: 12, endColumn: 16},
+			{text: "Equity:Opening-Balances", kind: SyntaxAccount, column: 17, endColumn: 40},
 		}},
 		{lineNum: 2, isIndented: false, tokens: []Token{}},
 		{lineNum: 3, isIndented: false, tokens: []Token{
-			{text: "2000-01-01", isQuoted: false},
-			{text: "balance", isQuoted: false},
-			{text: "Assets:Bank", isQuoted: false},
-			{text: "0", isQuoted: false},
-			{text: "EUR", isQuoted: false},
+			{text: "2000-01-01", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "balance", kind: SyntaxWord, column: 12, endColumn: 19},
+			{text: "Assets:Bank", kind: SyntaxAccount, column: 20, endColumn: 31},
+			{text: "0", kind: SyntaxNumber, column: 41, endColumn: 42},
+			{text: "EUR", kind: SyntaxCurrency, column: 43, endColumn: 46},
 		}},
 		{lineNum: 4, isIndented: false, tokens: []Token{}},
 		{lineNum: 5, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
 		}},
 		{lineNum: 6, isIndented: true, tokens: []Token{
-			{text: "Assets:Bank", isQuoted: false},
+			{text: "Assets:Bank", kind: SyntaxAccount, column: 3, endColumn: 14},
 		}},
 		{lineNum: 7, isIndented: true, tokens: []Token{
-			{text: "Income:Job", isQuoted: false},
-			{text: "-100.00", isQuoted: false},
-			{text: "EUR", isQuoted: false},
+			{text: "Income:Job", kind: SyntaxAccount, column: 3, endColumn: 13},
+			{text: "-100.00", kind: SyntaxNumber, column: 14, endColumn: 21},
+			{text: "EUR", kind: SyntaxCurrency, column: 22, endColumn: 25},
 		}},
 		{lineNum: 8, isIndented: false},
 	}
@@ -60,15 +60,15 @@ func TestParseComment(t *testing.T) {
 	assert.Nil(t, err)
 	expected := []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-01", isQuoted: false},
-			{text: "open", isQuoted: false},
-			{text: "Equity:Opening-Balances", isQuoted: false},
+			{text: "2000-01-01", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "open", kind: SyntaxWord, column: 12, endColumn: 16},
+			{text: "Equity:Opening-Balances", kind: SyntaxAccount, column: 17, endColumn: 40},
 		}},
 		{lineNum: 2, isIndented: false, tokens: []Token{}},
 		{lineNum: 3, isIndented: false, tokens: []Token{
-			{text: "2000-01-01", isQuoted: false},
-			{text: "open", isQuoted: false},
-			{text: "Assets:Bank2", isQuoted: false},
+			{text: "2000-01-01", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "open", kind: SyntaxWord, column: 12, endColumn: 16},
+			{text: "Assets:Bank2", kind: SyntaxAccount, column: 17, endColumn: 29},
 		}},
 		{lineNum: 4, isIndented: false},
 	}
@@ -80,10 +80,10 @@ func TestParseQuotes(t *testing.T) {
 	assert.Nil(t, err)
 	expected := []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
-			{text: "Payee", isQuoted: true},
-			{text: "Narration", isQuoted: false},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
+			{text: "Payee", kind: SyntaxString, column: 14, endColumn: 21},
+			{text: "Narration", kind: SyntaxWord, column: 22, endColumn: 31},
 		}},
 		{lineNum: 2, isIndented: false},
 	}
@@ -93,10 +93,10 @@ func TestParseQuotes(t *testing.T) {
 	assert.Nil(t, err)
 	expected = []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
-			{text: "Payee", isQuoted: true},
-			{text: "Narration", isQuoted: true},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
+			{text: "Payee", kind: SyntaxString, column: 14, endColumn: 21},
+			{text: "Narration", kind: SyntaxString, column: 22, endColumn: 33},
 		}},
 		{lineNum: 2, isIndented: false},
 	}
@@ -106,10 +106,10 @@ func TestParseQuotes(t *testing.T) {
 	assert.Nil(t, err)
 	expected = []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
-			{text: "Payee", isQuoted: true},
-			{text: "Narration", isQuoted: true},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
+			{text: "Payee", kind: SyntaxString, column: 14, endColumn: 21},
+			{text: "Narration", kind: SyntaxString, column: 21, endColumn: 32},
 		}},
 		{lineNum: 2, isIndented: false},
 	}
@@ -119,10 +119,10 @@ func TestParseQuotes(t *testing.T) {
 	assert.Nil(t, err)
 	expected = []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
-			{text: "Payee", isQuoted: true},
-			{text: "Narration \" with quote", isQuoted: true},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
+			{text: "Payee", kind: SyntaxString, column: 14, endColumn: 21},
+			{text: "Narration \" with quote", kind: SyntaxString, column: 22, endColumn: 47},
 		}},
 		{lineNum: 2, isIndented: false},
 	}
@@ -135,15 +135,15 @@ multiline"
 	assert.Nil(t, err)
 	expected = []Line{
 		{lineNum: 1, isIndented: false, tokens: []Token{
-			{text: "2000-01-02", isQuoted: false},
-			{text: "*", isQuoted: false},
-			{text: "Payee", isQuoted: true},
-			{text: "Narration \nmulti\nmultiline", isQuoted: true},
+			{text: "2000-01-02", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "*", kind: SyntaxFlag, column: 12, endColumn: 13},
+			{text: "Payee", kind: SyntaxString, column: 14, endColumn: 21},
+			{text: "Narration \nmulti\nmultiline", kind: SyntaxString, column: 22, endColumn: 50},
 		}},
 		{lineNum: 4, isIndented: false, tokens: []Token{
-			{text: "2000-01-01", isQuoted: false},
-			{text: "open", isQuoted: false},
-			{text: "Assets:Bank1", isQuoted: false},
+			{text: "2000-01-01", kind: SyntaxDate, column: 1, endColumn: 11},
+			{text: "open", kind: SyntaxWord, column: 12, endColumn: 16},
+			{text: "Assets:Bank1", kind: SyntaxAccount, column: 17, endColumn: 29},
 		}},
 		{lineNum: 5, isIndented: false},
 	}
@@ -15,11 +15,32 @@ type Price struct {
 	amount   Amount
 }
 
+// Currency returns currency which is priced
+func (p Price) Currency() Currency {
+	return p.currency
+}
+
+// Amount returns price of one unit of the currency
+func (p Price) Amount() Amount {
+	return p.amount
+}
+
+// PricePoint is a price of a currency known on the date
 type PricePoint struct {
 	date   time.Time
 	amount Amount
 }
 
+// Date returns date of the price
+func (p PricePoint) Date() time.Time {
+	return p.date
+}
+
+// Amount returns price of one unit
+func (p PricePoint) Amount() Amount {
+	return p.amount
+}
+
 // Apply adds price to the inventory
 func (p Price) Apply(ls *LedgerState) error {
 	if _, ok := ls.prices[p.currency]; !ok {
@@ -75,3 +96,18 @@ func newPrice(lg LineGroup, fileName string) (Price, error) {
 	}
 	return d, nil
 }
+
+// latestPrice returns the last known price of the currency
+func (ls LedgerState) latestPrice(currency Currency) (PricePoint, bool) {
+	points := ls.prices[currency]
+	if len(points) == 0 {
+		return PricePoint{}, false
+	}
+	latest := points[0]
+	for _, p := range points[1:] {
+		if !p.date.Before(latest.date) {
+			latest = p
+		}
+	}
+	return latest, true
+}
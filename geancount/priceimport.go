@@ -0,0 +1,259 @@
+package geancount
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceOptions configures import of prices from files of brokers and central banks
+type PriceOptions struct {
+	// Delimiter separates fields of CSV files, comma by default
+	Delimiter string
+	// DateFormat is a layout of time.Parse or strftime format of dates in CSV files
+	DateFormat string
+	// DecimalSeparator of prices in CSV files is . or , the other one is a thousands separator
+	DecimalSeparator string
+	// Commodities limits imported prices to these commodities if it is not empty
+	Commodities []Currency
+	// ECBPricesOfEUR keeps ECB reference rates as prices of EUR in other currencies.
+	// By default they are inverted to prices of other currencies in EUR
+	ECBPricesOfEUR bool
+}
+
+// DefaultPriceOptions are options of CSV files with comma delimiter and ISO dates
+var DefaultPriceOptions = PriceOptions{Delimiter: ",", DateFormat: "2006-01-02", DecimalSeparator: "."}
+
+// Intervals of thinned prices
+const (
+	PriceIntervalWeekly  = "weekly"
+	PriceIntervalMonthly = "monthly"
+)
+
+// imported checks if prices of the commodity are imported
+func (o PriceOptions) imported(commodity Currency) bool {
+	return len(o.Commodities) == 0 || slices.Contains(o.Commodities, commodity)
+}
+
+// ImportPricesCSV converts rows with date, commodity, price and currency columns to prices sorted by date.
+// The first row is skipped if it is a header, rows which can not be converted are reported in the returned error
+func ImportPricesCSV(r io.Reader, opts PriceOptions) ([]Price, error) {
+	if utf8.RuneCountInString(opts.Delimiter) != 1 {
+		return nil, fmt.Errorf("delimiter %q should be one character", opts.Delimiter)
+	}
+	if opts.DecimalSeparator != "." && opts.DecimalSeparator != "," {
+		return nil, fmt.Errorf("decimal separator %q should be . or ,", opts.DecimalSeparator)
+	}
+	layout := dateLayout(opts.DateFormat)
+	numbers := CSVConfig{DecimalSeparator: opts.DecimalSeparator}
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(opts.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	prices := []Price{}
+	errs := []error{}
+	for first := true; ; first = false {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return prices, err
+		}
+		if isBlankRow(row) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(i int) string {
+			if i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(strings.TrimPrefix(row[i], "\ufeff"))
+		}
+		date, err := time.Parse(layout, cell(0))
+		if err != nil {
+			if !first {
+				errs = append(errs, fmt.Errorf("line %d: can not parse date %q", line, cell(0)))
+			}
+			continue
+		}
+		commodity, currency := Currency(strings.ToUpper(cell(1))), Currency(strings.ToUpper(cell(3)))
+		if !opts.imported(commodity) {
+			continue
+		}
+		value, err := numbers.parseNumber(cell(2))
+		if err == nil && cell(2) == "" {
+			err = errors.New("price is empty")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		if commodity == "" || !isCurrency(string(commodity)) {
+			errs = append(errs, fmt.Errorf("line %d: %q is not a valid commodity", line, commodity))
+			continue
+		}
+		if currency == "" || !isCurrency(string(currency)) {
+			errs = append(errs, fmt.Errorf("line %d: %q is not a valid currency", line, currency))
+			continue
+		}
+		prices = append(prices, NewPrice(date, commodity, NewAmount(value, currency)))
+	}
+	SortPrices(prices)
+	return prices, errors.Join(errs...)
+}
+
+// ecbEnvelope is a file of ECB euro foreign exchange reference rates like eurofxref-hist.xml
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ImportECBRates converts ECB euro reference rates to prices sorted by date. A rate is a price of EUR
+// in the currency, it is inverted to a price of the currency in EUR rounded to 8 decimal places
+// unless ECBPricesOfEUR is set
+func ImportECBRates(r io.Reader, opts PriceOptions) ([]Price, error) {
+	envelope := ecbEnvelope{}
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	prices := []Price{}
+	errs := []error{}
+	for _, day := range envelope.Days {
+		date, err := parseDate(day.Time)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can not parse date %q", day.Time))
+			continue
+		}
+		for _, rate := range day.Rates {
+			currency := Currency(rate.Currency)
+			value, err := decimal.NewFromString(rate.Rate)
+			if err != nil || !value.IsPositive() {
+				errs = append(errs, fmt.Errorf("%s: can not parse rate %q of %s", day.Time, rate.Rate, currency))
+				continue
+			}
+			if opts.ECBPricesOfEUR {
+				if opts.imported("EUR") {
+					prices = append(prices, NewPrice(date, "EUR", NewAmount(value, currency)))
+				}
+				continue
+			}
+			if opts.imported(currency) {
+				price := roundedQuotient(decimal.NewFromInt(1), value)
+				prices = append(prices, NewPrice(date, currency, NewAmount(price, "EUR")))
+			}
+		}
+	}
+	SortPrices(prices)
+	return prices, errors.Join(errs...)
+}
+
+// SortPrices sorts prices by date, commodity and currency
+func SortPrices(prices []Price) {
+	slices.SortStableFunc(prices, func(a, b Price) int {
+		return cmp.Or(a.date.Compare(b.date), cmp.Compare(a.currency, b.currency),
+			cmp.Compare(a.amount.currency, b.amount.currency))
+	})
+}
+
+// priceKey identifies prices of the commodity in the currency on the date
+type priceKey struct {
+	date      time.Time
+	commodity Currency
+	currency  Currency
+}
+
+// UniquePrices keeps only the first price of each commodity in each currency on a date
+func UniquePrices(prices []Price) []Price {
+	seen := map[priceKey]bool{}
+	unique := []Price{}
+	for _, p := range prices {
+		key := priceKey{p.date, p.currency, p.amount.currency}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+// MissingPrices returns prices of periods of the interval for which the state has no price of the commodity
+// in the same currency. Without interval only prices on the same date are known
+func (ls LedgerState) MissingPrices(prices []Price, interval string) ([]Price, error) {
+	period, err := pricePeriod(interval)
+	if err != nil {
+		return nil, err
+	}
+	known := map[priceKey]bool{}
+	for commodity, points := range ls.prices {
+		for _, p := range points {
+			known[priceKey{period(p.date), commodity, p.amount.currency}] = true
+		}
+	}
+	missing := []Price{}
+	for _, p := range prices {
+		if !known[priceKey{period(p.date), p.currency, p.amount.currency}] {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// pricePeriod returns a function which converts a date to the first day of its period of the interval,
+// weeks start on Monday and dates are kept if the interval is empty
+func pricePeriod(interval string) (func(time.Time) time.Time, error) {
+	switch interval {
+	case "":
+		return func(date time.Time) time.Time { return date }, nil
+	case PriceIntervalWeekly:
+		return func(date time.Time) time.Time {
+			return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+		}, nil
+	case PriceIntervalMonthly:
+		return func(date time.Time) time.Time {
+			return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown interval %s, only %s and %s are supported", interval,
+		PriceIntervalWeekly, PriceIntervalMonthly)
+}
+
+// ThinPrices keeps the last price of each commodity in each currency per week or month
+// depending on the interval, weeks start on Monday
+func ThinPrices(prices []Price, interval string) ([]Price, error) {
+	if interval == "" {
+		return nil, fmt.Errorf("interval should be %s or %s", PriceIntervalWeekly, PriceIntervalMonthly)
+	}
+	period, err := pricePeriod(interval)
+	if err != nil {
+		return nil, err
+	}
+	last := map[priceKey]int{}
+	for i, p := range prices {
+		key := priceKey{period(p.date), p.currency, p.amount.currency}
+		if j, ok := last[key]; !ok || !p.date.Before(prices[j].date) {
+			last[key] = i
+		}
+	}
+	thinned := []Price{}
+	for i, p := range prices {
+		if last[priceKey{period(p.date), p.currency, p.amount.currency}] == i {
+			thinned = append(thinned, p)
+		}
+	}
+	return thinned, nil
+}
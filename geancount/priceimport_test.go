@@ -0,0 +1,163 @@
+package geancount
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// priceLines returns prices in short form like 2024-06-03 VWCE 118.50 EUR
+func priceLines(prices []Price) []string {
+	lines := make([]string, len(prices))
+	for i, p := range prices {
+		lines[i] = formatDate(p.Date()) + " " + string(p.Currency()) + " " + sourceAmount(p.Amount())
+	}
+	return lines
+}
+
+func TestImportPricesCSV(t *testing.T) {
+	file, err := os.Open("testdata/prices.csv")
+	assert.Nil(t, err)
+	defer file.Close()
+	prices, err := ImportPricesCSV(file, DefaultPriceOptions)
+	assert.Equal(t, []string{
+		"2024-06-03 VWCE 118.50 EUR",
+		"2024-06-04 VWCE 119.02 EUR",
+		"2024-06-07 VWCE 120.10 EUR",
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-06-10 VWCE 119.80 EUR",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(prices))
+	assert.ErrorContains(t, err, "line 7: price is empty")
+	assert.ErrorContains(t, err, `line 8: can not parse date "06/12/2024"`)
+
+	opts := DefaultPriceOptions
+	opts.Delimiter = ";"
+	opts.DateFormat = "%d.%m.%Y"
+	opts.DecimalSeparator = ","
+	opts.Commodities = []Currency{"VWCE"}
+	src := "01.07.2024;VWCE;1.122,40;EUR\n01.07.2024;AAPL;210,10;USD\n"
+	prices, err = ImportPricesCSV(strings.NewReader(src), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2024-07-01 VWCE 1122.40 EUR"}, priceLines(prices))
+}
+
+func TestImportECBRates(t *testing.T) {
+	file, err := os.Open("testdata/eurofxref.xml")
+	assert.Nil(t, err)
+	defer file.Close()
+	opts := DefaultPriceOptions
+	opts.Commodities = []Currency{"USD", "GBP"}
+	prices, err := ImportECBRates(file, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2024-06-27 GBP 1.17987139 EUR",
+		"2024-06-27 USD 0.93492895 EUR",
+		"2024-06-28 GBP 1.1815024 EUR",
+		"2024-06-28 USD 0.93414292 EUR",
+	}, priceLines(prices))
+
+	_, err = file.Seek(0, 0)
+	assert.Nil(t, err)
+	opts = DefaultPriceOptions
+	opts.ECBPricesOfEUR = true
+	prices, err = ImportECBRates(file, opts)
+	assert.Nil(t, err)
+	assert.Len(t, prices, 6)
+	assert.Equal(t, "2024-06-27 EUR 1.0696 USD", priceLines(prices)[2])
+}
+
+func TestMissingPrices(t *testing.T) {
+	missing := func(prices []Price, err error) []Price {
+		assert.Nil(t, err)
+		return prices
+	}
+	ledger := NewLedger()
+	src := "2024-06-04 price VWCE 119.00 EUR\n2024-06-04 price VWCE 130.00 USD\n2024-06-10 price VWCE 119.80 EUR\n"
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(src)))
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+
+	file, err := os.Open("testdata/prices.csv")
+	assert.Nil(t, err)
+	defer file.Close()
+	prices, _ := ImportPricesCSV(file, DefaultPriceOptions)
+	assert.Equal(t, []string{
+		"2024-06-03 VWCE 118.50 EUR",
+		"2024-06-07 VWCE 120.10 EUR",
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(missing(ls.MissingPrices(prices, ""))))
+
+	// The last price of June is in the ledger so no other price of June is missing
+	monthly, err := ThinPrices(prices, PriceIntervalMonthly)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(missing(ls.MissingPrices(monthly, PriceIntervalMonthly))))
+
+	// The week of 2024-06-07 has a price in the ledger on another day
+	weekly, err := ThinPrices(prices, PriceIntervalWeekly)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(missing(ls.MissingPrices(weekly, PriceIntervalWeekly))))
+
+	_, err = ls.MissingPrices(prices, "daily")
+	assert.NotNil(t, err)
+}
+
+func TestUniquePrices(t *testing.T) {
+	date := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	prices := []Price{
+		NewPrice(date, "VWCE", NewAmount(decimal.RequireFromString("118.50"), "EUR")),
+		NewPrice(date, "VWCE", NewAmount(decimal.RequireFromString("130.00"), "USD")),
+		NewPrice(date, "VWCE", NewAmount(decimal.RequireFromString("118.60"), "EUR")),
+		NewPrice(date.AddDate(0, 0, 1), "VWCE", NewAmount(decimal.RequireFromString("118.60"), "EUR")),
+	}
+	assert.Equal(t, []string{
+		"2024-06-03 VWCE 118.50 EUR",
+		"2024-06-03 VWCE 130.00 USD",
+		"2024-06-04 VWCE 118.60 EUR",
+	}, priceLines(UniquePrices(prices)))
+
+	SortPrices(prices)
+	assert.Equal(t, Currency("USD"), prices[2].Amount().Currency())
+}
+
+func TestThinPrices(t *testing.T) {
+	file, err := os.Open("testdata/prices.csv")
+	assert.Nil(t, err)
+	defer file.Close()
+	prices, _ := ImportPricesCSV(file, DefaultPriceOptions)
+
+	weekly, err := ThinPrices(prices, PriceIntervalWeekly)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2024-06-07 VWCE 120.10 EUR",
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-06-10 VWCE 119.80 EUR",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(weekly))
+
+	monthly, err := ThinPrices(prices, PriceIntervalMonthly)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2024-06-10 AAPL 193.12 USD",
+		"2024-06-10 VWCE 119.80 EUR",
+		"2024-07-01 VWCE 122.40 EUR",
+	}, priceLines(monthly))
+
+	_, err = ThinPrices(prices, "daily")
+	assert.NotNil(t, err)
+
+	b := strings.Builder{}
+	assert.Nil(t, WriteDirectives(&b, []Directive{monthly[0], monthly[1]}, DefaultFormatOptions))
+	assert.Equal(t, 2, strings.Count(b.String(), "\n"))
+}
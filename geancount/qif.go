@@ -0,0 +1,409 @@
+package geancount
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// QIFConfig describes how a QIF file exported by Quicken is converted to a ledger
+type QIFConfig struct {
+	// Account is the account of transactions before any !Account header, ContraAccount gets
+	// transactions without a category
+	ImportConfig `yaml:",inline"`
+	// Currency of all amounts, QIF has no currencies
+	Currency Currency `yaml:"currency"`
+	// DateOrder is an order of day, month and year in dates: mdy by default, dmy or ymd
+	DateOrder string `yaml:"date_order"`
+	// Accounts are accounts of QIF accounts and categories by their names like Food:Groceries
+	Accounts map[string]AccountName `yaml:"accounts"`
+}
+
+// LoadQIFConfig reads the config from YAML file
+func LoadQIFConfig(filename string) (QIFConfig, error) {
+	return loadImportConfig(filename, ParseQIFConfig)
+}
+
+// ParseQIFConfig parses YAML config, fills defaults and checks it
+func ParseQIFConfig(data []byte) (QIFConfig, error) {
+	config := QIFConfig{ImportConfig: defaultImportConfig, DateOrder: "mdy"}
+	if err := parseImportConfig(data, &config, &config.ImportConfig); err != nil {
+		return config, err
+	}
+	if config.Currency == "" || !isCurrency(string(config.Currency)) {
+		return config, fmt.Errorf("currency %q is not a valid currency", config.Currency)
+	}
+	if !slices.Contains([]string{"mdy", "dmy", "ymd"}, config.DateOrder) {
+		return config, fmt.Errorf("date_order %q should be mdy, dmy or ymd", config.DateOrder)
+	}
+	for name, account := range config.Accounts {
+		if !isAccount(string(account)) {
+			return config, fmt.Errorf("account %q of %s is not a valid account", account, name)
+		}
+	}
+	return config, nil
+}
+
+// qifRecord is fields of a record ending with ^, fields of splits are kept in order
+type qifRecord struct {
+	line   int
+	fields map[byte]string
+	splits []qifSplit
+	lines  []string
+}
+
+type qifSplit struct {
+	category, memo, amount string
+}
+
+// qifLeg is a posting of a transaction to other account than the account of the list
+type qifLeg struct {
+	account  AccountName
+	amount   decimal.Decimal
+	transfer bool
+}
+
+type qifTransaction struct {
+	t       Transaction
+	account AccountName
+	legs    []qifLeg
+	split   bool
+}
+
+// qifTransferKey identifies a transfer from the account of the list to other account
+type qifTransferKey struct {
+	date   time.Time
+	from   AccountName
+	to     AccountName
+	amount string
+}
+
+// qifImport is a state of conversion of a QIF file
+type qifImport struct {
+	config       QIFConfig
+	account      AccountName
+	accounts     map[string]AccountName
+	categories   map[string]bool
+	transactions []qifTransaction
+	directives   []Directive
+	investments  map[AccountName]int
+	errs         []error
+}
+
+// ImportQIF converts accounts, categories, transactions and prices of a QIF file to a ledger with
+// opening of accounts. Transfers between accounts of the file are converted once.
+// Investment transactions and records which can not be converted are reported in the returned error
+func ImportQIF(r io.Reader, config QIFConfig) ([]Directive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		data = decodeWindows1252(data)
+	}
+	q := &qifImport{
+		config:      config,
+		account:     config.Account,
+		accounts:    map[string]AccountName{},
+		categories:  map[string]bool{},
+		investments: map[AccountName]int{},
+	}
+	section := ""
+	// Accounts of a list between !Option:AutoSwitch and !Clear:AutoSwitch do not start transactions
+	accountList := false
+	record := qifRecord{fields: map[byte]string{}}
+	for i, line := range strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] == '!' {
+			switch header := strings.ToLower(strings.TrimSpace(line)); header {
+			case "!option:autoswitch":
+				accountList = true
+			case "!clear:autoswitch":
+				accountList = false
+			default:
+				section = header
+			}
+			continue
+		}
+		if line[0] == '^' {
+			q.record(section, record, accountList)
+			record = qifRecord{fields: map[byte]string{}}
+			continue
+		}
+		if record.line == 0 {
+			record.line = i + 1
+		}
+		record.lines = append(record.lines, line)
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch {
+		case code == 'S':
+			record.splits = append(record.splits, qifSplit{category: value})
+		case code == 'E' && len(record.splits) > 0:
+			record.splits[len(record.splits)-1].memo = value
+		case code == '$' && len(record.splits) > 0:
+			record.splits[len(record.splits)-1].amount = value
+		default:
+			if _, ok := record.fields[code]; !ok {
+				record.fields[code] = value
+			}
+		}
+	}
+	if record.line != 0 {
+		q.record(section, record, accountList)
+	}
+
+	directives := q.directives
+	for _, t := range q.dedupedTransactions() {
+		directives = append(directives, t)
+	}
+	currencies := map[AccountName][]Currency{}
+	for _, account := range q.accounts {
+		currencies[account] = []Currency{config.Currency}
+	}
+	for _, d := range directives {
+		if t, ok := d.(Transaction); ok {
+			for _, p := range t.postings {
+				currencies[p.account] = []Currency{config.Currency}
+			}
+		}
+	}
+	accounts := make([]AccountName, 0, len(q.investments))
+	for account := range q.investments {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+	for _, account := range accounts {
+		q.errs = append(q.errs, fmt.Errorf("%d investment transactions of %s are not converted", q.investments[account], account))
+	}
+	directives = append(openAccounts(directives, currencies), directives...)
+	slices.SortStableFunc(directives, compareDirectives)
+	return directives, errors.Join(q.errs...)
+}
+
+// record converts the record of the section
+func (q *qifImport) record(section string, record qifRecord, accountList bool) {
+	switch section {
+	case "!account":
+		name := record.fields['N']
+		root := "Assets"
+		if t := strings.ToLower(record.fields['T']); t == "ccard" || t == "oth l" {
+			root = "Liabilities"
+		}
+		account, ok := q.config.Accounts[name]
+		if !ok {
+			account = migratedAccount(root, strings.Split(name, ":"))
+		}
+		q.accounts[name] = account
+		if !accountList {
+			q.account = account
+		}
+	case "!type:cat":
+		_, income := record.fields['I']
+		q.categories[record.fields['N']] = income
+	case "!type:bank", "!type:cash", "!type:ccard", "!type:oth a", "!type:oth l":
+		t, err := q.transaction(record)
+		if err != nil {
+			q.errs = append(q.errs, fmt.Errorf("line %d: %w", record.line, err))
+			return
+		}
+		q.transactions = append(q.transactions, t)
+	case "!type:invst":
+		q.investments[q.account]++
+	case "!type:prices":
+		for _, line := range record.lines {
+			price, err := q.price(line)
+			if err != nil {
+				q.errs = append(q.errs, fmt.Errorf("line %d: %w", record.line, err))
+				continue
+			}
+			q.directives = append(q.directives, price)
+		}
+	case "!type:class", "!type:memorized", "!type:security":
+		// Classes, memorized transactions and securities are not needed in the ledger
+	default:
+		q.errs = append(q.errs, fmt.Errorf("line %d: records of %q are not converted", record.line, section))
+	}
+}
+
+// category returns the account of the category or the transfer like [Savings] and if it is a transfer.
+// Categories which are not in the category list are incomes if money comes from them to the account
+func (q *qifImport) category(category string, amount decimal.Decimal) (AccountName, bool) {
+	category, _, _ = strings.Cut(category, "/") // Class
+	category = strings.TrimSpace(category)
+	if strings.HasPrefix(category, "[") && strings.HasSuffix(category, "]") {
+		name := category[1 : len(category)-1]
+		account, ok := q.accounts[name]
+		if !ok {
+			account, ok = q.config.Accounts[name]
+		}
+		if !ok {
+			account = migratedAccount("Assets", strings.Split(name, ":"))
+		}
+		if account == q.account {
+			return openingBalancesAccount, false
+		}
+		return account, true
+	}
+	if category == "" {
+		return q.config.ContraAccount, false
+	}
+	if account, ok := q.config.Accounts[category]; ok {
+		return account, false
+	}
+	names := strings.Split(category, ":")
+	income, ok := q.categories[category]
+	if !ok {
+		income, ok = q.categories[names[0]]
+	}
+	if !ok {
+		income = amount.IsNegative()
+	}
+	if income {
+		return migratedAccount("Income", names), false
+	}
+	return migratedAccount("Expenses", names), false
+}
+
+// transaction converts a record of a bank or cash list, splits become postings
+func (q *qifImport) transaction(record qifRecord) (qifTransaction, error) {
+	date, err := qifDate(record.fields['D'], q.config.DateOrder)
+	if err != nil {
+		return qifTransaction{}, err
+	}
+	s, ok := record.fields['T']
+	if !ok {
+		s = record.fields['U']
+	}
+	value, err := qifAmount(s)
+	if err != nil {
+		return qifTransaction{}, err
+	}
+	qt := qifTransaction{account: q.account, split: len(record.splits) > 0}
+	postings := []Posting{NewPosting(q.account, NewAmount(value, q.config.Currency))}
+	if !qt.split {
+		account, transfer := q.category(record.fields['L'], value.Neg())
+		qt.legs = append(qt.legs, qifLeg{account: account, amount: value.Neg(), transfer: transfer})
+		postings = append(postings, NewPosting(account, Amount{}))
+	}
+	rest := value
+	for _, split := range record.splits {
+		amount, err := qifAmount(split.amount)
+		if err != nil {
+			return qifTransaction{}, err
+		}
+		account, transfer := q.category(split.category, amount.Neg())
+		qt.legs = append(qt.legs, qifLeg{account: account, amount: amount.Neg(), transfer: transfer})
+		posting := NewPosting(account, NewAmount(amount.Neg(), q.config.Currency))
+		if split.memo != "" {
+			posting = posting.WithMeta("memo", split.memo)
+		}
+		postings = append(postings, posting)
+		rest = rest.Sub(amount)
+	}
+	if qt.split && !rest.IsZero() {
+		postings = append(postings, NewPosting(q.config.ContraAccount, Amount{}))
+	}
+	qt.t = NewTransaction(date, q.config.Flag, record.fields['P'], record.fields['M'], postings)
+	return qt, nil
+}
+
+// dedupedTransactions returns transactions without the second side of transfers between accounts of the file.
+// Transfers of split transactions are kept and the other side is dropped
+func (q *qifImport) dedupedTransactions() []Transaction {
+	transfers := map[qifTransferKey]int{}
+	key := func(qt qifTransaction, leg qifLeg) qifTransferKey {
+		return qifTransferKey{qt.t.date, qt.account, leg.account, leg.amount.Neg().String()}
+	}
+	for _, qt := range q.transactions {
+		if !qt.split {
+			continue
+		}
+		for _, leg := range qt.legs {
+			if leg.transfer {
+				transfers[key(qt, leg)]++
+			}
+		}
+	}
+	result := []Transaction{}
+	for _, qt := range q.transactions {
+		if !qt.split && qt.legs[0].transfer {
+			leg := qt.legs[0]
+			mirror := qifTransferKey{qt.t.date, leg.account, qt.account, leg.amount.String()}
+			if transfers[mirror] > 0 {
+				transfers[mirror]--
+				continue
+			}
+			transfers[key(qt, leg)]++
+		}
+		result = append(result, qt.t)
+	}
+	return result
+}
+
+// price converts a line of the price list like "AAPL",150.25,"1/ 2'24"
+func (q *qifImport) price(line string) (Price, error) {
+	fields, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil || len(fields) < 3 {
+		return Price{}, fmt.Errorf("can not parse price %q", line)
+	}
+	value, err := qifAmount(fields[1])
+	if err != nil {
+		return Price{}, err
+	}
+	date, err := qifDate(fields[2], q.config.DateOrder)
+	if err != nil {
+		return Price{}, err
+	}
+	return NewPrice(date, migratedCurrency(fields[0]), NewAmount(value, q.config.Currency)), nil
+}
+
+func qifAmount(s string) (decimal.Decimal, error) {
+	value, err := decimal.NewFromString(strings.NewReplacer(",", "", " ", "").Replace(s))
+	if err != nil {
+		return value, fmt.Errorf("can not parse amount %q", s)
+	}
+	return value, nil
+}
+
+// qifDate parses dates like 01/02/2024, 1/ 2/24 or 1/ 2'24, years after an apostrophe are in 2000s
+func qifDate(s string, order string) (time.Time, error) {
+	apostrophe := strings.Contains(s, "'")
+	parts := strings.FieldsFunc(strings.ReplaceAll(s, " ", ""), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("can not parse date %q", s)
+	}
+	numbers := map[byte]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("can not parse date %q", s)
+		}
+		numbers[order[i]] = n
+	}
+	year := numbers['y']
+	switch {
+	case year >= 100:
+	case apostrophe || year < 70:
+		year += 2000
+	default:
+		year += 1900
+	}
+	date := time.Date(year, time.Month(numbers['m']), numbers['d'], 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(numbers['m']) || date.Day() != numbers['d'] {
+		return time.Time{}, fmt.Errorf("can not parse date %q", s)
+	}
+	return date, nil
+}
@@ -0,0 +1,107 @@
+package geancount
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQIFConfig(t *testing.T) {
+	config, err := ParseQIFConfig([]byte("account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: USD\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "mdy", config.DateOrder)
+
+	for _, src := range []string{
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: USD\ndate_order: ddd\n",
+		"account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: USD\naccounts: {Food: Food}\n",
+	} {
+		_, err := ParseQIFConfig([]byte(src))
+		assert.NotNil(t, err, src)
+	}
+}
+
+func TestImportQIF(t *testing.T) {
+	config, err := ParseQIFConfig([]byte("account: Assets:Bank\ncontra_account: Expenses:Unknown\ncurrency: USD\n" +
+		"accounts:\n  Household: Expenses:Home\n"))
+	assert.Nil(t, err)
+	file, err := os.Open("testdata/quicken.qif")
+	assert.Nil(t, err)
+	defer file.Close()
+	directives, err := ImportQIF(file, config)
+	assert.Equal(t, "line 58: can not parse date \"13/13'24\"\n"+
+		"1 investment transactions of Assets:Brokerage are not converted", err.Error())
+
+	opens := directivesOf[AccountOpen](directives)
+	accounts := []AccountName{}
+	for _, open := range opens {
+		accounts = append(accounts, open.Account())
+		assert.Equal(t, []Currency{"USD"}, open.Currencies())
+	}
+	slices.Sort(accounts)
+	assert.Equal(t, []AccountName{"Assets:Brokerage", "Assets:Checking", "Assets:Savings", "Equity:Opening-Balances",
+		"Expenses:Food", "Expenses:Food:Groceries", "Expenses:Home", "Expenses:Unknown", "Income:Salary",
+		"Liabilities:Visa"}, accounts)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), opens[0].Date())
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), opens[4].Date())
+
+	transactions := directivesOf[Transaction](directives)
+	// The transfer is converted once
+	assert.Equal(t, 5, len(transactions))
+	shopping := transactions[1]
+	assert.Equal(t, "City Market", shopping.Payee())
+	assert.Equal(t, "Weekly shopping", shopping.Narration())
+	postings := shopping.Postings()
+	assert.Equal(t, 4, len(postings))
+	assert.Equal(t, map[string]string{"memo": "Vegetables"}, postings[1].Meta())
+	assert.Equal(t, AccountName("Expenses:Unknown"), postings[3].Account())
+
+	prices := directivesOf[Price](directives)
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, Currency("AAPL"), prices[0].Currency())
+	assert.Equal(t, "150.25 USD", sourceAmount(prices[0].Amount()))
+
+	ls := loadImported(t, directives)
+	assert.Equal(t, "2933.30", ls.Balance("Assets:Checking")["USD"].StringFixed(2))
+	assert.Equal(t, "500.00", ls.Balance("Assets:Savings")["USD"].StringFixed(2))
+	assert.Equal(t, "12.50", ls.Balance("Expenses:Food")["USD"].StringFixed(2))
+	assert.Equal(t, "4.20", ls.Balance("Expenses:Unknown")["USD"].StringFixed(2))
+	assert.Equal(t, "-1000.00", ls.Balance("Equity:Opening-Balances")["USD"].StringFixed(2))
+
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives, DefaultFormatOptions))
+}
+
+func TestQIFDate(t *testing.T) {
+	for s, expected := range map[string]string{
+		"1/ 2'24":    "2024-01-02",
+		"01/02/2024": "2024-01-02",
+		"1/2/99":     "1999-01-02",
+		"1/2/05":     "2005-01-02",
+		"12/31' 4":   "2004-12-31",
+	} {
+		date, err := qifDate(s, "mdy")
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, formatDate(date), s)
+	}
+	date, err := qifDate("31.12.2024", "dmy")
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-12-31", formatDate(date))
+	_, err = qifDate("2/30/2024", "mdy")
+	assert.NotNil(t, err)
+}
+
+// directivesOf returns directives of the type T
+func directivesOf[T Directive](directives []Directive) []T {
+	result := []T{}
+	for _, d := range directives {
+		if typed, ok := d.(T); ok {
+			result = append(result, typed)
+		}
+	}
+	return result
+}
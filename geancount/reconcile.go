@@ -0,0 +1,169 @@
+package geancount
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// clearedMetaKey is a key of metadata of postings cleared with a statement
+const clearedMetaKey = "cleared"
+
+// UnclearedPosting is a posting to the reconciled account which is not cleared yet
+type UnclearedPosting struct {
+	transaction Transaction
+	posting     Posting
+}
+
+// Transaction returns the transaction of the posting
+func (u UnclearedPosting) Transaction() Transaction {
+	return u.transaction
+}
+
+// Posting returns the posting with its amount computed if it was blank
+func (u UnclearedPosting) Posting() Posting {
+	return u.posting
+}
+
+// Reconciliation compares the balance of the account in the ledger with a bank statement
+type Reconciliation struct {
+	account   AccountName
+	end       time.Time
+	statement Amount
+	balance   Amount
+	uncleared []UnclearedPosting
+	file      string
+}
+
+// Reconcile computes the balance of the account at the end of the statement end date and finds
+// uncleared postings until that date. Postings of transactions flagged with ! are uncleared and if
+// clearedMeta is set postings without cleared metadata are uncleared too. The ledger is not reconciled if its
+// state has errors because directives which fail to apply are left out of the balance
+func (l *Ledger) Reconcile(account AccountName, end time.Time, statement Amount, clearedMeta bool) (Reconciliation, error) {
+	if _, err := l.GetState(); err != nil {
+		return Reconciliation{}, fmt.Errorf("ledger has errors, fix them before reconciling:\n%w", err)
+	}
+	ls := l.stateAt(end)
+	if _, ok := ls.accounts[account]; !ok {
+		return Reconciliation{}, fmt.Errorf("account %s is not open on %s", account, formatDate(end))
+	}
+	r := Reconciliation{
+		account:   account,
+		end:       end,
+		statement: statement,
+		balance:   NewAmount(ls.balances[account][statement.currency], statement.currency),
+		uncleared: []UnclearedPosting{},
+	}
+	for _, t := range ls.transactions {
+		for _, p := range t.postings {
+			if p.account != account || p.amount.currency != statement.currency {
+				continue
+			}
+			_, cleared := p.meta[clearedMetaKey]
+			if t.status == "!" || clearedMeta && !cleared {
+				r.uncleared = append(r.uncleared, UnclearedPosting{transaction: t, posting: p})
+			}
+		}
+	}
+	if location, ok := ls.lastPostings[account]; ok && location.FileName != "" {
+		r.file = location.FileName
+	}
+	for _, d := range l.directives {
+		if open, ok := d.(AccountOpen); ok && open.account == account && r.file == "" {
+			r.file = open.FileName()
+		}
+	}
+	return r, nil
+}
+
+// Account returns the reconciled account
+func (r Reconciliation) Account() AccountName {
+	return r.account
+}
+
+// Statement returns the closing balance of the statement
+func (r Reconciliation) Statement() Amount {
+	return r.statement
+}
+
+// Balance returns the balance of the account in the ledger at the end of the statement
+func (r Reconciliation) Balance() Amount {
+	return r.balance
+}
+
+// Uncleared returns uncleared postings until the end of the statement
+func (r Reconciliation) Uncleared() []UnclearedPosting {
+	return r.uncleared
+}
+
+// UnclearedTotal returns the sum of uncleared postings
+func (r Reconciliation) UnclearedTotal() Amount {
+	total := decimal.Zero
+	for _, u := range r.uncleared {
+		total = total.Add(u.posting.amount.value)
+	}
+	return NewAmount(total, r.statement.currency)
+}
+
+// ClearedBalance returns the ledger balance without uncleared postings
+func (r Reconciliation) ClearedBalance() Amount {
+	return NewAmount(r.balance.value.Sub(r.UnclearedTotal().value), r.statement.currency)
+}
+
+// Difference returns the statement balance minus the ledger balance
+func (r Reconciliation) Difference() Amount {
+	return NewAmount(r.statement.value.Sub(r.balance.value), r.statement.currency)
+}
+
+// Reconciled checks if the ledger balance matches the statement
+func (r Reconciliation) Reconciled() bool {
+	return r.Difference().value.Abs().LessThan(defaultPrecision)
+}
+
+// File returns the file with the latest posting to the account or its opening where
+// the balance directive belongs
+func (r Reconciliation) File() string {
+	return r.file
+}
+
+// BalanceDirective returns the balance of the statement asserted on the day after its end
+func (r Reconciliation) BalanceDirective() Balance {
+	return NewBalance(r.end.AddDate(0, 0, 1), r.account, r.statement)
+}
+
+// AppendBalance appends the balance directive to the file of the account if the ledger matches the statement
+func (r Reconciliation) AppendBalance() error {
+	if !r.Reconciled() {
+		return fmt.Errorf("the ledger differs from the statement by %s", r.Difference())
+	}
+	if r.file == "" {
+		return fmt.Errorf("file of %s is unknown", r.account)
+	}
+	src, err := os.ReadFile(r.file)
+	if err != nil {
+		return err
+	}
+	b := strings.Builder{}
+	if len(src) > 0 && !bytes.HasSuffix(src, []byte("\n")) {
+		b.WriteString("\n")
+	}
+	if len(src) > 0 {
+		b.WriteString("\n")
+	}
+	if err := writeDirective(&b, r.BalanceDirective()); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.file, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,75 @@
+package geancount
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadFile("testdata/reconcile/main.bean"))
+	end := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	statement := NewAmount(decimal.RequireFromString("950.00"), "EUR")
+
+	r, err := ledger.Reconcile("Assets:Bank", end, statement, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "938 EUR", r.Balance().String())
+	assert.Len(t, r.Uncleared(), 1)
+	assert.Equal(t, "Lunch", r.Uncleared()[0].Transaction().Narration())
+	assert.Equal(t, "-12 EUR", r.Uncleared()[0].Posting().Amount().String())
+	assert.Equal(t, "950 EUR", r.ClearedBalance().String())
+	assert.Equal(t, "12 EUR", r.Difference().String())
+	assert.False(t, r.Reconciled())
+	assert.Equal(t, "bank.bean", filepath.Base(r.File()))
+	assert.NotNil(t, r.AppendBalance())
+
+	r, err = ledger.Reconcile("Assets:Bank", end, statement, true)
+	assert.Nil(t, err)
+	assert.Len(t, r.Uncleared(), 2)
+	assert.Equal(t, "-42 EUR", r.UnclearedTotal().String())
+
+	_, err = ledger.Reconcile("Assets:Savings", end, statement, false)
+	assert.NotNil(t, err)
+}
+
+func TestReconcileAppendBalance(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.bean", "bank.bean"} {
+		src, err := os.ReadFile(filepath.Join("testdata/reconcile", name))
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), src, 0o644))
+	}
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadFile(filepath.Join(dir, "main.bean")))
+	end := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	r, err := ledger.Reconcile("Assets:Bank", end, NewAmount(decimal.RequireFromString("938.00"), "EUR"), false)
+	assert.Nil(t, err)
+	assert.True(t, r.Reconciled())
+	assert.Nil(t, r.AppendBalance())
+
+	src, err := os.ReadFile(filepath.Join(dir, "bank.bean"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(src), "  Assets:Bank\n\n2024-07-01 balance Assets:Bank 938.00 EUR\n")
+
+	ledger = NewLedger()
+	assert.Nil(t, ledger.LoadFile(filepath.Join(dir, "main.bean")))
+	_, err = ledger.GetState()
+	assert.Nil(t, err)
+}
+
+func TestReconcileLedgerWithErrors(t *testing.T) {
+	src := "2024-01-01 open Assets:Bank EUR\n2024-01-01 open Expenses:Food EUR\n\n" +
+		"2024-06-20 * \"Shop\"\n  Expenses:Food  30.00 USD\n  Assets:Bank\n"
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", strings.NewReader(src)))
+	end := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	_, err := ledger.Reconcile("Assets:Bank", end, NewAmount(decimal.Zero, "EUR"), false)
+	assert.ErrorContains(t, err, "ledger has errors")
+	assert.Equal(t, CodeCurrencyNotAllowed, Diagnostics(err)[0].Code)
+}
@@ -0,0 +1,130 @@
+package geancount
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[1;31m"
+	colorYellow = "\033[1;33m"
+	colorBlue   = "\033[1;34m"
+	colorCyan   = "\033[1;36m"
+)
+
+// DiagnosticRenderer renders diagnostics with excerpts of the source and underlined tokens
+type DiagnosticRenderer struct {
+	readFile func(name string) ([]byte, error)
+	color    bool
+	sources  map[string][]string
+}
+
+// NewDiagnosticRenderer creates DiagnosticRenderer, readFile is used to get sources like os.ReadFile.
+// If color is true output contains ANSI escape codes
+func NewDiagnosticRenderer(readFile func(name string) ([]byte, error), color bool) *DiagnosticRenderer {
+	return &DiagnosticRenderer{readFile: readFile, color: color, sources: map[string][]string{}}
+}
+
+func (r *DiagnosticRenderer) paint(color string, s string) string {
+	if !r.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case SeverityError:
+		return colorRed
+	case SeverityWarning:
+		return colorYellow
+	default:
+		return colorCyan
+	}
+}
+
+// sourceLine returns the line of the file or false if it is not available
+func (r *DiagnosticRenderer) sourceLine(fileName string, lineNum int) (string, bool) {
+	lines, ok := r.sources[fileName]
+	if !ok {
+		src, err := r.readFile(fileName)
+		if err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		r.sources[fileName] = lines
+	}
+	if lineNum < 1 || lineNum > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[lineNum-1], "\r"), true
+}
+
+// Render writes the diagnostic
+func (r *DiagnosticRenderer) Render(w io.Writer, d Diagnostic) {
+	color := severityColor(d.Severity)
+	header := d.Severity.String()
+	if d.Code != "" {
+		header += "[" + d.Code + "]"
+	}
+	fmt.Fprintf(w, "%s%s\n", r.paint(color, header), r.paint(colorBold, ": "+d.Message))
+
+	gutter := len(fmt.Sprint(d.Line))
+	for _, related := range d.Related {
+		gutter = max(gutter, len(fmt.Sprint(related.Line)))
+	}
+	r.renderExcerpt(w, d.Location, gutter, color, "")
+	if d.Hint != "" {
+		fmt.Fprintf(w, "%s %s %s\n", strings.Repeat(" ", gutter), r.paint(colorBlue, "="), r.paint(colorBold, "hint:")+" "+d.Hint)
+	}
+	for _, related := range d.Related {
+		r.renderExcerpt(w, related.Location, gutter, colorBlue, related.Message)
+	}
+	fmt.Fprintln(w)
+}
+
+// renderExcerpt writes the location, the source line and marks the span under it
+func (r *DiagnosticRenderer) renderExcerpt(w io.Writer, l Location, gutter int, color string, label string) {
+	pad := strings.Repeat(" ", gutter)
+	position := l.FileName
+	if l.Line > 0 {
+		position = fmt.Sprintf("%s:%d", l.FileName, l.Line)
+		if l.Column > 0 {
+			position = fmt.Sprintf("%s:%d", position, l.Column)
+		}
+	}
+	fmt.Fprintf(w, "%s%s %s\n", pad, r.paint(colorBlue, "-->"), position)
+	source, ok := r.sourceLine(l.FileName, l.Line)
+	if !ok {
+		if label != "" {
+			fmt.Fprintf(w, "%s %s %s\n", pad, r.paint(colorBlue, "="), label)
+		}
+		return
+	}
+	bar := r.paint(colorBlue, "|")
+	fmt.Fprintf(w, "%s %s\n", pad, bar)
+	fmt.Fprintf(w, "%s %s %s\n", r.paint(colorBlue, fmt.Sprintf("%*d", gutter, l.Line)), bar, source)
+	if l.Column == 0 {
+		if label != "" {
+			fmt.Fprintf(w, "%s %s %s\n", pad, bar, r.paint(color, label))
+		}
+		return
+	}
+	// Keep tabs so the marker is under the token whatever tab width is
+	marker := strings.Builder{}
+	runes := []rune(source)
+	for i := 0; i < l.Column-1 && i < len(runes); i++ {
+		if runes[i] == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	underline := strings.Repeat("^", max(1, l.EndColumn-l.Column))
+	if label != "" {
+		underline += " " + label
+	}
+	fmt.Fprintf(w, "%s %s %s%s\n", pad, bar, marker.String(), r.paint(color, underline))
+}
@@ -0,0 +1,54 @@
+package geancount
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderDiagnostic(t *testing.T) {
+	sources := map[string]string{
+		"main.bean": "2000-01-01 pad Assets:Bank Income:Job\n\t2000-01-02\tbalance Assets:Bank 1 EUR\n",
+	}
+	readFile := func(name string) ([]byte, error) {
+		src, ok := sources[name]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return []byte(src), nil
+	}
+	renderer := NewDiagnosticRenderer(readFile, false)
+	sb := strings.Builder{}
+	renderer.Render(&sb, Diagnostic{
+		Location: Location{FileName: "main.bean", Line: 2, Column: 20, EndColumn: 25},
+		Severity: SeverityError,
+		Code:     CodeBalanceMismatch,
+		Message:  "Balance mismatch",
+		Hint:     "difference is 1 EUR",
+		Related: []RelatedLocation{
+			{Location: Location{FileName: "main.bean", Line: 1, Column: 16, EndColumn: 27}, Message: "pad is here"},
+			{Location: Location{FileName: "other.bean", Line: 3}, Message: "not readable"},
+		},
+	})
+	expected := "error[E-BALANCE-MISMATCH]: Balance mismatch\n" +
+		" --> main.bean:2:20\n" +
+		"  |\n" +
+		"2 | \t2000-01-02\tbalance Assets:Bank 1 EUR\n" +
+		"  | \t          \t       ^^^^^\n" +
+		"  = hint: difference is 1 EUR\n" +
+		" --> main.bean:1:16\n" +
+		"  |\n" +
+		"1 | 2000-01-01 pad Assets:Bank Income:Job\n" +
+		"  |                ^^^^^^^^^^^ pad is here\n" +
+		" --> other.bean:3\n" +
+		"  = not readable\n" +
+		"\n"
+	assert.Equal(t, expected, sb.String())
+
+	renderer = NewDiagnosticRenderer(readFile, true)
+	sb.Reset()
+	renderer.Render(&sb, Diagnostic{Location: Location{FileName: "main.bean", Line: 1}, Severity: SeverityWarning, Message: "warning"})
+	assert.True(t, strings.HasPrefix(sb.String(), colorYellow+"warning"+colorReset))
+}
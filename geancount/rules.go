@@ -0,0 +1,171 @@
+package geancount
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Rules categorize imported transactions, the first matching rule is applied
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule sets the contra account, tags and payee of transactions matching all its conditions
+type Rule struct {
+	// Payee is a regular expression matching the payee
+	Payee string `yaml:"payee"`
+	// Narration is a regular expression matching the narration
+	Narration string `yaml:"narration"`
+	// Account is the account of the statement
+	Account AccountName `yaml:"account"`
+	// Min and Max are inclusive bounds of the amount posted to the statement account, spending is negative
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+	// ContraAccount replaces the account of the blank posting
+	ContraAccount AccountName `yaml:"contra_account"`
+	// Tags are added to the transaction
+	Tags []string `yaml:"tags"`
+	// SetPayee replaces the payee, $1 and ${name} are replaced with groups matched by Payee
+	SetPayee string `yaml:"set_payee"`
+
+	payee     *regexp.Regexp
+	narration *regexp.Regexp
+	min       *decimal.Decimal
+	max       *decimal.Decimal
+}
+
+// LoadRules reads rules from YAML file
+func LoadRules(filename string) (Rules, error) {
+	return loadImportConfig(filename, ParseRules)
+}
+
+// ParseRules parses YAML rules and compiles their expressions
+func ParseRules(data []byte) (Rules, error) {
+	rules := Rules{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return rules, err
+	}
+	for i := range rules.Rules {
+		if err := rules.Rules[i].compile(); err != nil {
+			return rules, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.Payee != "" {
+		if r.payee, err = regexp.Compile(r.Payee); err != nil {
+			return fmt.Errorf("payee: %w", err)
+		}
+	}
+	if r.Narration != "" {
+		if r.narration, err = regexp.Compile(r.Narration); err != nil {
+			return fmt.Errorf("narration: %w", err)
+		}
+	}
+	bound := func(name string, s string) (*decimal.Decimal, error) {
+		if s == "" {
+			return nil, nil
+		}
+		value, err := decimal.NewFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse %s %q", name, s)
+		}
+		return &value, nil
+	}
+	if r.min, err = bound("min", r.Min); err != nil {
+		return err
+	}
+	if r.max, err = bound("max", r.Max); err != nil {
+		return err
+	}
+	for name, account := range map[string]AccountName{"account": r.Account, "contra_account": r.ContraAccount} {
+		if account != "" && !isAccount(string(account)) {
+			return fmt.Errorf("%s %q is not a valid account", name, account)
+		}
+	}
+	for _, tag := range r.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t#") {
+			return fmt.Errorf("tag %q is not valid", tag)
+		}
+	}
+	return nil
+}
+
+// matches checks conditions of the rule, the amount is taken from the first posting
+// to the rule account or the first posting with an amount
+func (r Rule) matches(t Transaction) bool {
+	if r.payee != nil && !r.payee.MatchString(t.payee) {
+		return false
+	}
+	if r.narration != nil && !r.narration.MatchString(t.narration) {
+		return false
+	}
+	i := slices.IndexFunc(t.postings, func(p Posting) bool {
+		return p.amount.currency != "" && (r.Account == "" || p.account == r.Account)
+	})
+	if i == -1 {
+		return r.Account == "" && r.min == nil && r.max == nil
+	}
+	value := t.postings[i].amount.value
+	return (r.min == nil || value.GreaterThanOrEqual(*r.min)) && (r.max == nil || value.LessThanOrEqual(*r.max))
+}
+
+// apply changes the transaction, the blank posting gets the contra account
+func (r Rule) apply(t Transaction) Transaction {
+	if r.ContraAccount != "" {
+		t = withBlankAccount(t, r.ContraAccount)
+	}
+	if r.SetPayee != "" {
+		payee := r.SetPayee
+		if r.payee != nil {
+			payee = string(r.payee.ExpandString(nil, r.SetPayee, t.payee, r.payee.FindStringSubmatchIndex(t.payee)))
+		}
+		t.payee = payee
+	}
+	return t.WithTags(r.Tags...)
+}
+
+// withBlankAccount returns the transaction with the account of the blank posting replaced
+func withBlankAccount(t Transaction, account AccountName) Transaction {
+	i := slices.IndexFunc(t.postings, func(p Posting) bool { return p.amount.currency == "" })
+	if i != -1 {
+		t.postings = slices.Clone(t.postings)
+		t.postings[i].account = account
+	}
+	return t
+}
+
+// Categorize applies the first matching rule to every imported transaction.
+// Transactions without a matching rule get the contra account suggested by the classifier if it is not nil
+func (r Rules) Categorize(directives []Directive, classifier *Classifier) []Directive {
+	result := make([]Directive, len(directives))
+	for i, d := range directives {
+		result[i] = d
+		t, ok := d.(Transaction)
+		if !ok {
+			continue
+		}
+		j := slices.IndexFunc(r.Rules, func(rule Rule) bool { return rule.matches(t) })
+		switch {
+		case j != -1:
+			result[i] = r.Rules[j].apply(t)
+		case classifier != nil:
+			if account, ok := classifier.Suggest(t); ok {
+				result[i] = withBlankAccount(t, account)
+			}
+		}
+	}
+	return result
+}
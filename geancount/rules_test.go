@@ -0,0 +1,81 @@
+package geancount
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte("rules:\n  - payee: (?i)cafe\n    min: -10\n    contra_account: Expenses:Coffee\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rules.Rules))
+	assert.Equal(t, "-10", rules.Rules[0].Min)
+
+	for _, src := range []string{
+		"rules:\n  - payee: \"(\"\n",
+		"rules:\n  - max: ten\n",
+		"rules:\n  - contra_account: Coffee\n",
+		"rules:\n  - tags: [\"two words\"]\n",
+		"rules:\n  - unknown: 1\n",
+	} {
+		_, err := ParseRules([]byte(src))
+		assert.NotNil(t, err, src)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - payee: "(?i)^AMZN Mktp (\\w+)"
+    set_payee: Amazon $1
+    contra_account: Expenses:Shopping
+    tags: [online]
+  - narration: (?i)salary
+    account: Assets:Bank
+    min: 1000
+    contra_account: Income:Job
+  - payee: (?i)cafe
+    max: -0.01
+    min: -20
+    contra_account: Expenses:Coffee
+`))
+	assert.Nil(t, err)
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bank := func(payee string, narration string, amount string) Transaction {
+		return NewTransaction(date, "*", payee, narration, []Posting{
+			NewPosting("Assets:Bank", NewAmount(decimal.RequireFromString(amount), "EUR")),
+			NewPosting("Expenses:Unknown", Amount{}),
+		})
+	}
+	imported := []Directive{
+		bank("AMZN Mktp DE*123", "", "-30"),
+		bank("ACME", "Salary January", "2500"),
+		bank("ACME", "Salary bonus", "50"),
+		bank("Corner Cafe", "", "-4.50"),
+		bank("Corner Cafe", "Catering", "-120"),
+		NewBalance(date, "Assets:Bank", NewAmount(decimal.Zero, "EUR")),
+	}
+	result := rules.Categorize(imported, nil)
+	contra := func(i int) AccountName {
+		return result[i].(Transaction).Postings()[1].Account()
+	}
+	amazon := result[0].(Transaction)
+	assert.Equal(t, "Amazon DE", amazon.Payee())
+	assert.Equal(t, []string{"online"}, amazon.Tags())
+	assert.Equal(t, AccountName("Expenses:Shopping"), contra(0))
+	assert.Equal(t, AccountName("Income:Job"), contra(1))
+	assert.Equal(t, AccountName("Expenses:Unknown"), contra(2))
+	assert.Equal(t, AccountName("Expenses:Coffee"), contra(3))
+	assert.Equal(t, AccountName("Expenses:Unknown"), contra(4))
+	assert.Equal(t, imported[5], result[5])
+	// Imported directives are not changed
+	assert.Equal(t, AccountName("Expenses:Unknown"), imported[0].(Transaction).Postings()[1].Account())
+
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, result[:1], DefaultFormatOptions))
+	assert.Equal(t, "2024-01-02 * \"Amazon DE\" \"\" #online\n  Assets:Bank  -30 EUR\n  Expenses:Shopping\n", out.String())
+}
@@ -0,0 +1,52 @@
+package geancount
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Server is a http.Handler serving the HTML report of a ledger file.
+// The ledger is reloaded when the file or any of its includes changes
+type Server struct {
+	watcher *ledgerWatcher
+	report  atomic.Pointer[htmlReport]
+}
+
+// NewServer loads the ledger file and creates Server for it
+func NewServer(filename string) *Server {
+	s := &Server{}
+	s.watcher = newLedgerWatcher(filename, func(snapshot *ledgerSnapshot) {
+		s.report.Store(newHTMLReport(snapshot.state, snapshot.err))
+	})
+	return s
+}
+
+// Watch reloads the ledger when its files change until ctx is done
+func (s *Server) Watch(ctx context.Context, interval time.Duration) {
+	s.watcher.watch(ctx, interval)
+}
+
+// ServeHTTP renders the requested page of the report
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		path = "index.html"
+	}
+	// The report is taken once so the whole request uses the same state
+	report := s.report.Load()
+	buf := bytes.Buffer{}
+	if err := report.renderPage(path, &buf); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,52 @@
+package geancount
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer("testdata/basic.bean")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "Assets:Bank"))
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/accounts/Assets.Bank.html", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "79.50 EUR"))
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.html", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServerReload(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.bean")
+	included := filepath.Join(dir, "included.bean")
+	os.WriteFile(main, []byte("2000-01-01 open Assets:Bank\ninclude \"included.bean\"\n"), 0o644)
+	os.WriteFile(included, []byte("2000-01-01 open Assets:Cash\n"), 0o644)
+
+	server := NewServer(main)
+	assert.False(t, server.watcher.changed())
+	_, ok := server.report.Load().ls.accounts["Assets:Wallet"]
+	assert.False(t, ok)
+
+	os.WriteFile(included, []byte("2000-01-01 open Assets:Cash\n2000-01-01 open Assets:Wallet\n"), 0o644)
+	// Ensure modification time differs on filesystems with coarse resolution
+	os.Chtimes(included, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	assert.True(t, server.watcher.changed())
+	server.watcher.reload()
+	_, ok = server.report.Load().ls.accounts["Assets:Wallet"]
+	assert.True(t, ok)
+}
@@ -0,0 +1,261 @@
+package geancount
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// sqlSchemaVersion is stored in PRAGMA user_version and is increased on incompatible changes
+const sqlSchemaVersion = 1
+
+var sqlSchema = []string{
+	`CREATE TABLE accounts (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	)`,
+	`CREATE TABLE account_periods (
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		open_date TEXT NOT NULL,
+		close_date TEXT
+	)`,
+	`CREATE TABLE account_currencies (
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		currency TEXT NOT NULL,
+		PRIMARY KEY (account_id, currency)
+	)`,
+	`CREATE TABLE transactions (
+		id INTEGER PRIMARY KEY,
+		date TEXT NOT NULL,
+		status TEXT NOT NULL,
+		payee TEXT NOT NULL,
+		narration TEXT NOT NULL,
+		file TEXT NOT NULL,
+		line INTEGER NOT NULL
+	)`,
+	`CREATE TABLE postings (
+		id INTEGER PRIMARY KEY,
+		transaction_id INTEGER NOT NULL REFERENCES transactions(id),
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		value TEXT NOT NULL,
+		currency TEXT NOT NULL,
+		price_value TEXT,
+		price_currency TEXT,
+		at_cost INTEGER NOT NULL
+	)`,
+	`CREATE TABLE prices (
+		id INTEGER PRIMARY KEY,
+		date TEXT NOT NULL,
+		currency TEXT NOT NULL,
+		value TEXT NOT NULL,
+		quote_currency TEXT NOT NULL,
+		file TEXT NOT NULL,
+		line INTEGER NOT NULL
+	)`,
+	`CREATE TABLE balance_assertions (
+		id INTEGER PRIMARY KEY,
+		date TEXT NOT NULL,
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		value TEXT NOT NULL,
+		currency TEXT NOT NULL,
+		file TEXT NOT NULL,
+		line INTEGER NOT NULL
+	)`,
+	`CREATE TABLE lots (
+		id INTEGER PRIMARY KEY,
+		account_id INTEGER NOT NULL REFERENCES accounts(id),
+		currency TEXT NOT NULL,
+		units TEXT NOT NULL,
+		cost_value TEXT NOT NULL,
+		cost_currency TEXT NOT NULL,
+		date TEXT NOT NULL,
+		label TEXT NOT NULL
+	)`,
+	`CREATE INDEX postings_transaction_id ON postings(transaction_id)`,
+	`CREATE INDEX postings_account_id ON postings(account_id)`,
+}
+
+// sqlTables are dropped before export in reverse order of dependencies
+var sqlTables = []string{"lots", "balance_assertions", "prices", "postings", "transactions", "account_currencies", "account_periods", "accounts"}
+
+// sqlExporter keeps the transaction and ids of exported accounts
+type sqlExporter struct {
+	tx       *sql.Tx
+	accounts map[AccountName]int64
+}
+
+// ExportSQL writes the ledger into normalized tables of the SQLite database.
+// Existing tables are replaced. Decimal values are stored as TEXT to keep the precision
+func (l *Ledger) ExportSQL(ls LedgerState, db *sql.DB) error {
+	// Foreign keys are enabled per connection and not inside a transaction, so the pragma and
+	// the transaction use one connection of the pool
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	var foreignKeys int
+	if err := tx.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+		tx.Rollback()
+		return cmp.Or(err, errors.New("foreign keys are not enforced"))
+	}
+	e := sqlExporter{tx: tx, accounts: map[AccountName]int64{}}
+	if err := e.export(l, ls); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *sqlExporter) export(l *Ledger, ls LedgerState) error {
+	for _, table := range sqlTables {
+		if _, err := e.tx.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range sqlSchema {
+		if _, err := e.tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := e.tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqlSchemaVersion)); err != nil {
+		return err
+	}
+	if err := e.exportAccounts(ls); err != nil {
+		return err
+	}
+	if err := e.exportTransactions(ls); err != nil {
+		return err
+	}
+	if err := e.exportDirectives(l); err != nil {
+		return err
+	}
+	return e.exportLots(ls)
+}
+
+func (e *sqlExporter) exportAccounts(ls LedgerState) error {
+	names := make([]AccountName, 0, len(ls.accounts))
+	for name := range ls.accounts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		acc := ls.accounts[name]
+		res, err := e.tx.Exec("INSERT INTO accounts (name) VALUES (?)", string(name))
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		e.accounts[name] = id
+		for i, opened := range acc.opened {
+			var closed any
+			if i < len(acc.closed) {
+				closed = formatDate(acc.closed[i])
+			}
+			_, err := e.tx.Exec("INSERT INTO account_periods (account_id, open_date, close_date) VALUES (?, ?, ?)",
+				id, formatDate(opened), closed)
+			if err != nil {
+				return err
+			}
+		}
+		currencies := make([]Currency, 0, len(acc.currencies))
+		for c := range acc.currencies {
+			currencies = append(currencies, c)
+		}
+		slices.Sort(currencies)
+		for _, c := range currencies {
+			if _, err := e.tx.Exec("INSERT INTO account_currencies (account_id, currency) VALUES (?, ?)", id, string(c)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *sqlExporter) exportTransactions(ls LedgerState) error {
+	for _, t := range ls.transactions {
+		res, err := e.tx.Exec("INSERT INTO transactions (date, status, payee, narration, file, line) VALUES (?, ?, ?, ?, ?, ?)",
+			formatDate(t.Date()), t.status, t.payee, t.narration, t.FileName(), t.LineNum())
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, p := range t.postings {
+			var priceValue, priceCurrency any
+			if p.price != nil {
+				priceValue = p.price.value.String()
+				priceCurrency = string(p.price.currency)
+			}
+			_, err := e.tx.Exec("INSERT INTO postings (transaction_id, account_id, value, currency, price_value, price_currency, at_cost) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				id, e.accounts[p.account], p.amount.value.String(), string(p.amount.currency), priceValue, priceCurrency, p.atCost)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *sqlExporter) exportDirectives(l *Ledger) error {
+	for _, directive := range l.directives {
+		var err error
+		switch d := directive.(type) {
+		case Price:
+			_, err = e.tx.Exec("INSERT INTO prices (date, currency, value, quote_currency, file, line) VALUES (?, ?, ?, ?, ?, ?)",
+				formatDate(d.Date()), string(d.currency), d.amount.value.String(), string(d.amount.currency), d.FileName(), d.LineNum())
+		case Balance:
+			id, ok := e.accounts[d.account]
+			if !ok { // Balance of unknown account is reported by GetState
+				continue
+			}
+			_, err = e.tx.Exec("INSERT INTO balance_assertions (date, account_id, value, currency, file, line) VALUES (?, ?, ?, ?, ?, ?)",
+				formatDate(d.Date()), id, d.amount.value.String(), string(d.amount.currency), d.FileName(), d.LineNum())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *sqlExporter) exportLots(ls LedgerState) error {
+	accounts := make([]AccountName, 0, len(ls.inventories))
+	for a := range ls.inventories {
+		accounts = append(accounts, a)
+	}
+	slices.Sort(accounts)
+	for _, a := range accounts {
+		currencies := make([]Currency, 0, len(ls.inventories[a]))
+		for c := range ls.inventories[a] {
+			currencies = append(currencies, c)
+		}
+		slices.Sort(currencies)
+		for _, c := range currencies {
+			for _, lot := range ls.inventories[a][c] {
+				_, err := e.tx.Exec("INSERT INTO lots (account_id, currency, units, cost_value, cost_currency, date, label) VALUES (?, ?, ?, ?, ?, ?, ?)",
+					e.accounts[a], string(c), lot.amount.value.String(), lot.cost.value.String(), string(lot.cost.currency), formatDate(lot.date), lot.label)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
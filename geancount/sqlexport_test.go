@@ -0,0 +1,42 @@
+package geancount
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestExportSQL(t *testing.T) {
+	ledger := NewLedger()
+	err := ledger.LoadFile("testdata/prices.bean")
+	assert.Nil(t, err)
+	ls, err := ledger.GetState()
+	assert.Nil(t, err)
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "out.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	// Export twice to check existing tables are replaced
+	assert.Nil(t, ledger.ExportSQL(ls, db))
+	assert.Nil(t, ledger.ExportSQL(ls, db))
+
+	var count int
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&count))
+	assert.Equal(t, 7, count)
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM balance_assertions").Scan(&count))
+	assert.Equal(t, 5, count)
+
+	var value string
+	err = db.QueryRow(`SELECT SUM(CAST(p.value AS REAL)) FROM postings p
+		JOIN accounts a ON a.id = p.account_id WHERE a.name = 'Assets:Bank'`).Scan(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, "-102", value)
+
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM lots WHERE currency = 'S2'").Scan(&count))
+	assert.Equal(t, 1, count)
+	assert.Nil(t, db.QueryRow("PRAGMA user_version").Scan(&count))
+	assert.Equal(t, sqlSchemaVersion, count)
+}
@@ -2,12 +2,15 @@ package geancount
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+// Lot is an amount of currency held at cost
 type Lot struct {
 	amount Amount
 	cost   Amount
@@ -19,12 +22,74 @@ func (l Lot) String() string {
 	return fmt.Sprintf("%s {%s} %s %s", l.amount, l.cost, l.date, l.label)
 }
 
+// Amount returns units of the lot
+func (l Lot) Amount() Amount {
+	return l.amount
+}
+
+// Cost returns cost of one unit
+func (l Lot) Cost() Amount {
+	return l.cost
+}
+
+// Date returns date when the lot was acquired
+func (l Lot) Date() time.Time {
+	return l.date
+}
+
+// Label returns label of the lot
+func (l Lot) Label() string {
+	return l.label
+}
+
 // Posting is a leg of a transaction
 type Posting struct {
-	account AccountName
-	amount  Amount
-	price   *Amount
-	atCost  bool
+	account     AccountName
+	amount      Amount
+	price       *Amount
+	atCost      bool
+	meta        map[string]string
+	accountSpan span
+	amountSpan  span
+}
+
+// Account returns account of the posting
+func (p Posting) Account() AccountName {
+	return p.account
+}
+
+// Amount returns amount of the posting, for blank posting it is known after the transaction is applied
+func (p Posting) Amount() Amount {
+	return p.amount
+}
+
+// Price returns price or cost of one unit, nil if the posting has none
+func (p Posting) Price() *Amount {
+	if p.price == nil {
+		return nil
+	}
+	price := *p.price
+	return &price
+}
+
+// AtCost returns true if the posting is held at cost
+func (p Posting) AtCost() bool {
+	return p.atCost
+}
+
+// Meta returns metadata of the posting like fitid of imported transactions
+func (p Posting) Meta() map[string]string {
+	return maps.Clone(p.meta)
+}
+
+// WithMeta returns copy of the posting with the metadata value set
+func (p Posting) WithMeta(key string, value string) Posting {
+	p.meta = maps.Clone(p.meta)
+	if p.meta == nil {
+		p.meta = map[string]string{}
+	}
+	p.meta[key] = value
+	return p
 }
 
 // Transaction is a movement from one account to another
@@ -33,9 +98,46 @@ type Transaction struct {
 	status    string
 	payee     string
 	narration string
+	tags      []string
 	postings  []Posting
 }
 
+// Status returns flag of the transaction like * or !
+func (t Transaction) Status() string {
+	return t.status
+}
+
+// Payee returns payee of the transaction
+func (t Transaction) Payee() string {
+	return t.payee
+}
+
+// Narration returns narration of the transaction
+func (t Transaction) Narration() string {
+	return t.narration
+}
+
+// Tags returns tags of the transaction without #
+func (t Transaction) Tags() []string {
+	return slices.Clone(t.tags)
+}
+
+// WithTags returns a copy of the transaction with tags added, tags it already has are skipped
+func (t Transaction) WithTags(tags ...string) Transaction {
+	t.tags = slices.Clone(t.tags)
+	for _, tag := range tags {
+		if !slices.Contains(t.tags, tag) {
+			t.tags = append(t.tags, tag)
+		}
+	}
+	return t
+}
+
+// Postings returns postings of the transaction
+func (t Transaction) Postings() []Posting {
+	return slices.Clone(t.postings)
+}
+
 // Apply balances postings of the transaction and changes balances
 func (t Transaction) Apply(ls *LedgerState) error {
 	// Balance postings
@@ -45,7 +147,7 @@ func (t Transaction) Apply(ls *LedgerState) error {
 	for i, posting := range t.postings {
 		if posting.amount.currency == "" {
 			if blankPostingIndex != -1 {
-				return fmt.Errorf("more than one empty posing")
+				return newDiagnostic(CodeMultipleBlankPostings, "more than one empty posing").at(posting.accountSpan)
 			}
 			blankPostingIndex = i
 		} else {
@@ -58,11 +160,11 @@ func (t Transaction) Apply(ls *LedgerState) error {
 				} else {
 					accountInventory, ok := ls.inventories[posting.account]
 					if !ok || len(accountInventory) == 0 {
-						return fmt.Errorf("No lots in %s", posting.account)
+						return newDiagnostic(CodeNoLots, "No lots in %s", posting.account).at(posting.amountSpan)
 					}
 					inventory, ok := ls.inventories[posting.account][posting.amount.currency]
 					if !ok || len(accountInventory) == 0 {
-						return fmt.Errorf("No lots for %s in %s", posting.amount.currency, posting.account)
+						return newDiagnostic(CodeNoLots, "No lots for %s in %s", posting.amount.currency, posting.account).at(posting.amountSpan)
 					}
 
 					totalAmount := decimal.Zero
@@ -72,7 +174,7 @@ func (t Transaction) Apply(ls *LedgerState) error {
 						totalValue = totalValue.Add(lot.amount.value.Mul(lot.cost.value))
 					}
 					if !totalAmount.Equal(posting.amount.value.Neg()) {
-						return fmt.Errorf("Not all lots are used")
+						return newDiagnostic(CodeLotsNotMatched, "Not all lots are used").at(posting.amountSpan)
 					}
 					effCurrency = inventory[0].cost.currency
 					effValue = totalValue.Neg()
@@ -83,6 +185,8 @@ func (t Transaction) Apply(ls *LedgerState) error {
 		}
 	}
 	if blankPostingIndex != -1 {
+		// Postings are shared with the directive which can be applied again
+		t.postings = slices.Clone(t.postings)
 		t.postings[blankPostingIndex].amount.currency = blankPostingCurrency
 		t.postings[blankPostingIndex].amount.value = decimal.Zero.Sub(blankPostingValue)
 	}
@@ -91,17 +195,23 @@ func (t Transaction) Apply(ls *LedgerState) error {
 	for _, posting := range t.postings {
 		acc, ok := ls.accounts[posting.account]
 		if !ok {
-			return fmt.Errorf("Posting to unknow account %s", posting.account)
+			return newDiagnostic(CodeAccountNotOpen, "Posting to unknow account %s", posting.account).at(posting.accountSpan)
 		}
 		if acc.IsClosed(t.Date()) {
-			return fmt.Errorf("Account %s is closed", posting.account)
+			return newDiagnostic(CodeAccountClosed, "Account %s is closed", posting.account).at(posting.accountSpan)
 		}
 		if !acc.CurrencyAllowed(posting.amount.currency) {
-			return fmt.Errorf("Currency %s can not be used in account %s", posting.amount.currency, posting.account)
+			return newDiagnostic(CodeCurrencyNotAllowed, "Currency %s can not be used in account %s", posting.amount.currency, posting.account).
+				at(posting.amountSpan)
 		}
 	}
 
 	for _, p := range t.postings {
+		if p.accountSpan.line != 0 {
+			ls.lastPostings[p.account] = p.accountSpan.location(t.FileName())
+		} else {
+			ls.lastPostings[p.account] = Location{FileName: t.FileName(), Line: t.LineNum()}
+		}
 		if !ls.accounts[p.account].hadTransactions {
 			acc := ls.accounts[p.account]
 			acc.hadTransactions = true
@@ -131,6 +241,7 @@ func (t Transaction) Apply(ls *LedgerState) error {
 			ls.balances[p.account][p.amount.currency] = ls.balances[p.account][p.amount.currency].Add(p.amount.value)
 		}
 	}
+	ls.transactions = append(ls.transactions, t)
 	return nil
 }
 
@@ -142,11 +253,21 @@ func newTransaction(lg LineGroup, fileName string) (Transaction, error) {
 	}
 	status := line.tokens[1].text
 	var payee, narration string
-	if len(line.tokens) >= 4 {
-		payee = line.tokens[2].text
-		narration = line.tokens[3].text
-	} else if len(line.tokens) >= 3 {
-		narration = line.tokens[2].text
+	strs := []string{}
+	tags := []string{}
+	for _, token := range line.tokens[2:] {
+		switch {
+		case token.kind == SyntaxString:
+			strs = append(strs, token.text)
+		case strings.HasPrefix(token.text, "#") && len(token.text) > 1:
+			tags = append(tags, token.text[1:])
+		}
+	}
+	if len(strs) >= 2 {
+		payee = strs[0]
+		narration = strs[1]
+	} else if len(strs) == 1 {
+		narration = strs[0]
 	}
 	if status == "txn" {
 		status = "*"
@@ -161,6 +282,7 @@ func newTransaction(lg LineGroup, fileName string) (Transaction, error) {
 		status:    status,
 		payee:     payee,
 		narration: narration,
+		tags:      tags,
 		postings:  postings,
 	}
 	return d, nil
@@ -171,11 +293,18 @@ func newPostings(lines []Line) ([]Posting, error) {
 	hasEmptyPosting := false
 	for _, line := range lines {
 		accountName := line.tokens[0].text
+		// Metadata after a posting belongs to it
+		if key, ok := metaKey(line); ok && len(postings) > 0 {
+			postings[len(postings)-1] = postings[len(postings)-1].WithMeta(key, line.tokens[1].text)
+			continue
+		}
 		if !strings.HasPrefix(accountName, "Assets:") && !strings.HasPrefix(accountName, "Equity:") && !strings.HasPrefix(accountName, "Income:") && !strings.HasPrefix(accountName, "Expenses:") && !strings.HasPrefix(accountName, "Liabilities:") {
 			continue
 		}
-		p := Posting{account: AccountName(accountName), amount: Amount{}}
+		p := Posting{account: AccountName(accountName), amount: Amount{}, accountSpan: tokenSpan(line, 0)}
+		p.amountSpan = p.accountSpan
 		if len(line.tokens) > 2 {
+			p.amountSpan = tokensSpan(line, 1, 2)
 			amountValue, err := decimal.NewFromString(strings.ReplaceAll(line.tokens[1].text, ",", ""))
 			if err != nil {
 				return postings, fmt.Errorf("can not parse amount value %s %s", accountName, line.tokens[1].text)
@@ -229,3 +358,15 @@ func newPostings(lines []Line) ([]Posting, error) {
 	}
 	return postings, nil
 }
+
+// metaKey returns key of the metadata line like `fitid: "123"`
+func metaKey(line Line) (string, bool) {
+	if len(line.tokens) < 2 {
+		return "", false
+	}
+	key, ok := strings.CutSuffix(line.tokens[0].text, ":")
+	if !ok || key == "" || key[0] < 'a' || key[0] > 'z' {
+		return "", false
+	}
+	return key, true
+}
@@ -1,6 +1,7 @@
 package geancount
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,3 +15,33 @@ func TestPriceInPostings(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, ls)
 }
+
+func TestPostingMeta(t *testing.T) {
+	src := "2000-01-01 open Assets:Bank\n2000-01-01 open Expenses:Food\n" +
+		"2000-01-02 * \"Food\"\n  Assets:Bank -1 EUR\n    fitid: \"F-1\"\n    note: \"a \\\"b\\\"\"\n  Expenses:Food\n"
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("meta.bean", strings.NewReader(src)))
+	postings := DirectivesOf[Transaction](ledger)[0].Postings()
+	assert.Equal(t, 2, len(postings))
+	assert.Equal(t, map[string]string{"fitid": "F-1", "note": "a \"b\""}, postings[0].Meta())
+	assert.Nil(t, postings[1].Meta())
+
+	posting := postings[1].WithMeta("k", "v")
+	assert.Equal(t, map[string]string{"k": "v"}, posting.Meta())
+	assert.Nil(t, postings[1].Meta())
+}
+
+func TestTransactionTags(t *testing.T) {
+	src := "2000-01-01 open Assets:Bank\n2000-01-01 open Expenses:Food\n" +
+		"2000-01-02 * \"Dinner\" #trip #food\n  Assets:Bank -1 EUR\n  Expenses:Food\n" +
+		"2000-01-03 * \"Cafe\" \"Lunch\" #trip\n  Assets:Bank -1 EUR\n  Expenses:Food\n"
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("tags.bean", strings.NewReader(src)))
+	transactions := DirectivesOf[Transaction](ledger)
+	assert.Equal(t, "", transactions[0].Payee())
+	assert.Equal(t, "Dinner", transactions[0].Narration())
+	assert.Equal(t, []string{"trip", "food"}, transactions[0].Tags())
+	assert.Equal(t, "Cafe", transactions[1].Payee())
+	assert.Equal(t, []string{"trip", "food"}, transactions[1].WithTags("food").Tags())
+	assert.Equal(t, []string{"trip"}, transactions[1].Tags())
+}
@@ -9,3 +9,19 @@ func formatDate(t time.Time) string {
 func parseDate(s string) (time.Time, error) {
 	return time.Parse("2006-01-02", s)
 }
+
+// splitErrors unwraps errors created with errors.Join into a flat list
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	errs := []error{}
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, splitErrors(e)...)
+	}
+	return errs
+}
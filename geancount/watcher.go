@@ -0,0 +1,120 @@
+package geancount
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// ledgerSnapshot is a result of loading a ledger file at some moment
+type ledgerSnapshot struct {
+	ledger   *Ledger
+	state    LedgerState
+	err      error
+	loadedAt time.Time
+	files    map[string]fileStamp
+}
+
+// fileStamp is used to detect that file is changed on disk
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func stampFile(filename string) fileStamp {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// ledgerWatcher keeps the latest snapshot of the ledger file and all its includes
+type ledgerWatcher struct {
+	filename string
+	cache    *Cache
+	snapshot atomic.Pointer[ledgerSnapshot]
+	onReload func(*ledgerSnapshot)
+}
+
+func newLedgerWatcher(filename string, onReload func(*ledgerSnapshot)) *ledgerWatcher {
+	w := &ledgerWatcher{filename: filename, cache: NewCache(), onReload: onReload}
+	w.reload()
+	return w
+}
+
+// reload loads the ledger and atomically replaces the current snapshot.
+// Only changed files are parsed again
+func (w *ledgerWatcher) reload() *ledgerSnapshot {
+	// Files are stamped before loading so a change made while they are read is detected by the next poll.
+	// Only files included for the first time are stamped after loading
+	stamps := map[string]fileStamp{w.filename: stampFile(w.filename)}
+	if previous := w.snapshot.Load(); previous != nil {
+		for f := range previous.files {
+			stamps[f] = stampFile(f)
+		}
+	}
+	stamp := func(f string) fileStamp {
+		if s, ok := stamps[f]; ok {
+			return s
+		}
+		return stampFile(f)
+	}
+	s := &ledgerSnapshot{ledger: NewLedger(), loadedAt: time.Now()}
+	s.ledger.UseCache(w.cache)
+	errs := []error{}
+	if err := s.ledger.LoadFile(w.filename); err != nil {
+		errs = append(errs, err)
+	}
+	state, err := s.ledger.GetState()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	s.state = state
+	s.err = errors.Join(errs...)
+	s.files = map[string]fileStamp{}
+	for _, f := range s.ledger.files {
+		s.files[f] = stamp(f)
+	}
+	// Directories of patterns are changed when a matching file is added or removed
+	for _, pattern := range s.ledger.patterns {
+		dir := filepath.Dir(pattern)
+		s.files[dir] = stamp(dir)
+	}
+	if w.onReload != nil {
+		w.onReload(s)
+	}
+	w.snapshot.Store(s)
+	return s
+}
+
+// changed checks if any of loaded files is changed since the snapshot was taken
+func (w *ledgerWatcher) changed() bool {
+	s := w.snapshot.Load()
+	for f, stamp := range s.files {
+		if stampFile(f) != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// watch polls loaded files and reloads the ledger when any of them changes
+func (w *ledgerWatcher) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.changed() {
+				w.reload()
+			}
+		}
+	}
+}
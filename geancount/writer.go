@@ -0,0 +1,176 @@
+package geancount
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// NewTransaction creates a transaction, a posting with zero Amount is blank and gets the rest of the balance
+func NewTransaction(date time.Time, status string, payee string, narration string, postings []Posting) Transaction {
+	return Transaction{
+		directive: directive{date: date},
+		status:    status,
+		payee:     payee,
+		narration: narration,
+		postings:  postings,
+	}
+}
+
+// NewPosting creates a posting of the amount to the account
+func NewPosting(account AccountName, amount Amount) Posting {
+	return Posting{account: account, amount: amount}
+}
+
+// NewAccountOpen creates opening of the account, currencies are optional
+func NewAccountOpen(date time.Time, account AccountName, currencies []Currency) AccountOpen {
+	allowed := map[Currency]struct{}{}
+	for _, c := range currencies {
+		allowed[c] = struct{}{}
+	}
+	return AccountOpen{directive: directive{date: date, order: accountOpenOrder}, account: account, currencies: allowed}
+}
+
+// NewBalance creates assertion of the amount in the account at the beginning of the date
+func NewBalance(date time.Time, account AccountName, amount Amount) Balance {
+	return Balance{directive: directive{date: date, order: balanceOrder}, account: account, amount: amount}
+}
+
+// NewPrice creates a price of the currency in the amount
+func NewPrice(date time.Time, currency Currency, amount Amount) Price {
+	return Price{directive: directive{date: date, order: priceOrder}, currency: currency, amount: amount}
+}
+
+// NewPostingAtCost creates a posting held at cost, a posting with nil cost reduces all lots of the currency
+func NewPostingAtCost(account AccountName, amount Amount, cost *Amount) Posting {
+	return Posting{account: account, amount: amount, price: cost, atCost: true}
+}
+
+// WriteDirectives writes directives as beancount text formatted with opts.
+// Transactions, balances, prices, pads and opening and closing of accounts are supported
+func WriteDirectives(w io.Writer, directives []Directive, opts FormatOptions) error {
+	return WriteImported(w, directives, nil, opts)
+}
+
+// WriteImported writes imported directives like WriteDirectives, duplicates are commented out
+// after a comment with the location of the existing directive
+func WriteImported(w io.Writer, directives []Directive, duplicates []Duplicate, opts FormatOptions) error {
+	existing := map[int]Directive{}
+	for _, d := range duplicates {
+		existing[d.index] = d.existing
+	}
+	b := strings.Builder{}
+	for i, d := range directives {
+		_, price := d.(Price)
+		_, previousPrice := directives[max(i-1, 0)].(Price)
+		// Prices are written as a list without blank lines
+		if i > 0 && !(price && previousPrice) {
+			b.WriteString("\n")
+		}
+		e, ok := existing[i]
+		if !ok {
+			if err := writeDirective(&b, d); err != nil {
+				return err
+			}
+			continue
+		}
+		text := strings.Builder{}
+		if err := writeDirective(&text, d); err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "; duplicate of %s:%d\n", e.FileName(), e.LineNum())
+		for _, line := range strings.SplitAfter(text.String(), "\n") {
+			if line != "" {
+				b.WriteString("; " + line)
+			}
+		}
+	}
+	_, err := w.Write(Format([]byte(b.String()), opts))
+	return err
+}
+
+func writeDirective(b *strings.Builder, d Directive) error {
+	date := formatDate(d.Date())
+	switch d := d.(type) {
+	case Transaction:
+		fmt.Fprintf(b, "%s %s", date, d.status)
+		if d.payee != "" {
+			fmt.Fprintf(b, " %s", quote(d.payee))
+		}
+		b.WriteString(" " + quote(d.narration))
+		for _, tag := range d.tags {
+			b.WriteString(" #" + tag)
+		}
+		b.WriteString("\n")
+		for _, p := range d.postings {
+			b.WriteString("  " + string(p.account))
+			if p.amount.currency != "" {
+				b.WriteString(" " + sourceAmount(p.amount))
+			}
+			switch {
+			case p.atCost && p.price == nil:
+				b.WriteString(" {}")
+			case p.atCost:
+				b.WriteString(" {" + sourceAmount(*p.price) + "}")
+			case p.price != nil:
+				b.WriteString(" @ " + sourceAmount(*p.price))
+			}
+			b.WriteString("\n")
+			keys := make([]string, 0, len(p.meta))
+			for key := range p.meta {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				fmt.Fprintf(b, "    %s: %s\n", key, quote(p.meta[key]))
+			}
+		}
+	case Balance:
+		fmt.Fprintf(b, "%s balance %s %s\n", date, d.account, sourceAmount(d.amount))
+	case Price:
+		fmt.Fprintf(b, "%s price %s %s\n", date, d.currency, sourceAmount(d.amount))
+	case Pad:
+		fmt.Fprintf(b, "%s pad %s %s\n", date, d.account, d.sourceAccount)
+	case AccountOpen:
+		fmt.Fprintf(b, "%s open %s", date, d.account)
+		if currencies := d.Currencies(); len(currencies) > 0 {
+			names := make([]string, len(currencies))
+			for i, c := range currencies {
+				names[i] = string(c)
+			}
+			b.WriteString(" " + strings.Join(names, ","))
+		}
+		b.WriteString("\n")
+	case AccountClose:
+		fmt.Fprintf(b, "%s close %s\n", date, d.account)
+	case Document:
+		fmt.Fprintf(b, "%s document %s %s\n", date, d.account, quote(d.path))
+	default:
+		return fmt.Errorf("can not write directive %T", d)
+	}
+	return nil
+}
+
+// sourceAmount formats the amount for a ledger file keeping trailing zeros of the value
+func sourceAmount(a Amount) string {
+	return fmt.Sprintf("%s %s", fixedString(a.value), a.currency)
+}
+
+func fixedString(v decimal.Decimal) string {
+	if v.Exponent() < 0 {
+		return v.StringFixed(-v.Exponent())
+	}
+	return v.String()
+}
+
+// escaper escapes backslashes and quotes in strings
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quote makes a string literal of s
+func quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
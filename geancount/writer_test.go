@@ -0,0 +1,69 @@
+package geancount
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDirectives(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	amount := NewAmount(decimal.RequireFromString("10.50"), "EUR")
+	directives := []Directive{
+		NewAccountOpen(date, "Assets:Bank", []Currency{"USD", "EUR"}),
+		NewTransaction(date, "!", "", "Say \"hi\"", []Posting{NewPosting("Assets:Bank", amount), NewPosting("Income:Job", Amount{})}),
+		Balance{directive: directive{date: date}, account: "Assets:Bank", amount: amount},
+		Price{directive: directive{date: date}, currency: "USD", amount: amount},
+		Pad{directive: directive{date: date}, account: "Assets:Bank", sourceAccount: "Equity:Opening"},
+		AccountClose{directive: directive{date: date}, account: "Assets:Bank"},
+	}
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives, DefaultFormatOptions))
+	assert.Equal(t, "2024-01-02 open Assets:Bank EUR,USD\n\n"+
+		"2024-01-02 ! \"Say \\\"hi\\\"\"\n  Assets:Bank                   10.50 EUR\n  Income:Job\n\n"+
+		"2024-01-02 balance Assets:Bank  10.50 EUR\n\n"+
+		"2024-01-02 price USD            10.50 EUR\n\n"+
+		"2024-01-02 pad Assets:Bank Equity:Opening\n\n"+
+		"2024-01-02 close Assets:Bank\n", out.String())
+
+	assert.NotNil(t, WriteDirectives(&out, []Directive{unknownDirective{}}, DefaultFormatOptions))
+}
+
+type unknownDirective struct {
+	directive
+}
+
+func (unknownDirective) Apply(*LedgerState) error {
+	return nil
+}
+
+func TestWriteDirectivesRoundTrip(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	amount := NewAmount(decimal.RequireFromString("10.50"), "EUR")
+	postings := []Posting{NewPosting("Expenses:Food", amount), NewPosting("Assets:Bank", Amount{})}
+	directives := []Directive{
+		NewAccountOpen(date, "Assets:Bank", nil),
+		NewAccountOpen(date, "Expenses:Food", nil),
+		NewTransaction(date, "*", `Shop\`, "Lunch", postings),
+		NewTransaction(date, "*", "Cafe \"Blue\"\nBerlin", `C:\receipts\`, postings),
+	}
+	out := bytes.Buffer{}
+	assert.Nil(t, WriteDirectives(&out, directives, DefaultFormatOptions))
+
+	ledger := NewLedger()
+	assert.Nil(t, ledger.LoadReader("main.bean", &out))
+	transactions := DirectivesOf[Transaction](ledger)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, `Shop\`, transactions[0].Payee())
+	assert.Equal(t, "Lunch", transactions[0].Narration())
+	assert.Equal(t, "Cafe \"Blue\"\nBerlin", transactions[1].Payee())
+	assert.Equal(t, `C:\receipts\`, transactions[1].Narration())
+	for _, tr := range transactions {
+		assert.Len(t, tr.Postings(), 2)
+	}
+	_, err := ledger.GetState()
+	assert.Nil(t, err)
+}